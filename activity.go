@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// activityOptions are the flags of the report subcommand.
+type activityOptions struct {
+	since       string
+	allBranches bool
+}
+
+// authorActivity counts the commits of one author across repositories.
+type authorActivity struct {
+	name    string
+	commits int
+	repos   map[string]bool
+}
+
+func (g *GitPullCommand) newActivityCommand() *cobra.Command {
+	opts := activityOptions{since: "7d"}
+
+	cmd := &cobra.Command{
+		Use:   "report <dir>",
+		Short: "Summarize recent commits per repository and per author",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			since, err := parseSince(opts.since, time.Now())
+			if err != nil {
+				g.logger.Errorf("Error: %v", err)
+				os.Exit(1)
+			}
+
+			stopSignals := g.handleSignals()
+			defer stopSignals()
+
+			if err := g.setupClient(); err != nil {
+				g.logger.Errorf("Error: %v", err)
+				os.Exit(1)
+			}
+			if err := g.requireGit("gitpull report"); err != nil {
+				g.logger.Errorf("Error: %v", err)
+				os.Exit(1)
+			}
+
+			authors := map[string]*authorActivity{}
+			g.forEachRepo(args[0], func(dir string) { g.recordActivity(dir, since, opts, authors) })
+			g.printActivity(since, authors)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.since, "since", opts.since, "Report commits newer than this age, e.g. 7d, 2w or 36h, or date (YYYY-MM-DD)")
+	cmd.Flags().BoolVar(&opts.allBranches, "all-branches", false, "Count commits on all local and remote-tracking branches, not only HEAD")
+
+	return cmd
+}
+
+// parseSince turns an age such as 7d, 2w or 36h, or a date, into the start
+// of the reported period.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(value, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				break
+			}
+			return now.Add(-time.Duration(count) * unit), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: expected an age such as 7d, 2w or 36h, or a date (YYYY-MM-DD)", value)
+}
+
+// recordActivity counts the commits of dir since the start of the period and
+// adds them to authors.
+func (g *GitPullCommand) recordActivity(dir string, since time.Time, opts activityOptions, authors map[string]*authorActivity) {
+	revs := []string{"HEAD"}
+	if opts.allBranches {
+		revs = []string{"--branches", "--remotes"}
+	}
+
+	var newest time.Time
+	if output, err := g.runner.Run(dir, append([]string{"log", "-1", "--format=%ct"}, revs...)...); err == nil {
+		if seconds, err := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64); err == nil {
+			newest = time.Unix(seconds, 0)
+		}
+	}
+
+	args := append([]string{"log", "--since=" + strconv.FormatInt(since.Unix(), 10), "--format=%an%x00%ae"}, revs...)
+	output, err := g.runner.Run(dir, args...)
+	if err != nil {
+		// Repositories without commits have no activity to report.
+		g.logger.Debugf("Could not read the log of %s: %v", dir, err)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	commits := 0
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		name, email, ok := strings.Cut(line, "\x00")
+		if err != nil || !ok {
+			continue
+		}
+		commits++
+		key := strings.ToLower(email)
+		a, ok := authors[key]
+		if !ok {
+			a = &authorActivity{name: name, repos: map[string]bool{}}
+			authors[key] = a
+		}
+		a.commits++
+		a.repos[dir] = true
+	}
+
+	if r := g.find(dir); r != nil {
+		r.Commits = commits
+		r.CommitDate = newest
+	}
+}
+
+// printActivity prints the repositories with commits in the period, the
+// authors of those commits and the repositories nobody touched.
+func (g *GitPullCommand) printActivity(since time.Time, authors map[string]*authorActivity) {
+	var active, untouched []*repoResult
+	for _, r := range g.summary {
+		if r.Commits > 0 {
+			active = append(active, r)
+		} else {
+			untouched = append(untouched, r)
+		}
+	}
+	sort.SliceStable(active, func(i, j int) bool { return active[i].Commits > active[j].Commits })
+	sort.SliceStable(untouched, func(i, j int) bool { return untouched[i].CommitDate.After(untouched[j].CommitDate) })
+
+	fmt.Printf("%s %s\n", g.msg(msgActivitySince), since.Format("2006-01-02 15:04"))
+	rows := make([][]string, 0, len(active))
+	for _, r := range active {
+		repoAuthors := 0
+		for _, a := range authors {
+			if a.repos[r.Dir] {
+				repoAuthors++
+			}
+		}
+		rows = append(rows, []string{r.Dir, strconv.Itoa(r.Commits), strconv.Itoa(repoAuthors), formatDate(r.CommitDate)})
+	}
+	g.renderTable([]string{g.msg(msgDirectory), g.msg(msgCommits), g.msg(msgAuthors), g.msg(msgDate)}, rows)
+
+	ranked := make([]*authorActivity, 0, len(authors))
+	for _, a := range authors {
+		ranked = append(ranked, a)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].commits != ranked[j].commits {
+			return ranked[i].commits > ranked[j].commits
+		}
+		return ranked[i].name < ranked[j].name
+	})
+	rows = make([][]string, 0, len(ranked))
+	for _, a := range ranked {
+		rows = append(rows, []string{a.name, strconv.Itoa(a.commits), strconv.Itoa(len(a.repos))})
+	}
+	fmt.Printf("\n%s\n", g.msg(msgActivityAuthors))
+	g.renderTable([]string{g.msg(msgAuthorName), g.msg(msgCommits), g.msg(msgRepositories)}, rows)
+
+	if len(untouched) > 0 {
+		rows = make([][]string, 0, len(untouched))
+		for _, r := range untouched {
+			rows = append(rows, []string{r.Dir, formatDate(r.CommitDate)})
+		}
+		fmt.Printf("\n%s\n", g.msg(msgActivityUntouched))
+		g.renderTable([]string{g.msg(msgDirectory), g.msg(msgDate)}, rows)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// branchPolicy only pulls repositories whose checked-out branch is allowed,
+// optionally switching to the remote's default branch first. Repositories
+// can override it per path in the config file, e.g.
+//
+//	repos:
+//	  - path: ~/src/experiments
+//	    protect-branches: ['.*']
+//	    checkout-default: false
+type branchPolicy struct {
+	protect         []string
+	checkoutDefault bool
+}
+
+func (g *GitPullCommand) addBranchFlags() {
+	flags := g.rootCmd.PersistentFlags()
+	flags.StringSliceVar(&g.branches.protect, "protect-branches", nil, "Only pull repositories on one of these branches, given as names or regular expressions, e.g. main,master,release/.*")
+	flags.BoolVar(&g.branches.checkoutDefault, "checkout-default", false, "Switch clean repositories to the remote's default branch before pulling")
+}
+
+// validateBranchPolicy compiles the branch patterns of the flags and the
+// config file.
+func (g *GitPullCommand) validateBranchPolicy() error {
+	if _, err := compileBranches(g.branches.protect); err != nil {
+		return fmt.Errorf("invalid --protect-branches: %v", err)
+	}
+	for _, o := range g.settings.perPath {
+		if _, err := compileBranches(o.ProtectBranches); err != nil {
+			return fmt.Errorf("invalid protect-branches for %s: %v", o.Path, err)
+		}
+	}
+	return nil
+}
+
+// compileBranches anchors every pattern, so a plain name only matches that
+// branch.
+func compileBranches(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return nil, err
+		}
+		re := regexp.MustCompile("^(?:" + p + ")$")
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// branchPolicyFor returns the policy of dir. Per-path settings win over
+// flags, later entries over earlier ones.
+func (g *GitPullCommand) branchPolicyFor(dir string) branchPolicy {
+	policy := g.branches
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = dir
+	}
+	for _, o := range g.settings.perPath {
+		if !o.matches(abs) {
+			continue
+		}
+		if o.ProtectBranches != nil {
+			policy.protect = o.ProtectBranches
+		}
+		if o.CheckoutDefault != nil {
+			policy.checkoutDefault = *o.CheckoutDefault
+		}
+	}
+	return policy
+}
+
+// checkBranch applies the branch policy before dir is pulled from remote.
+// It returns the status to finish with, or "" when the pull may go ahead.
+func (g *GitPullCommand) checkBranch(dir, remote string) (string, []byte) {
+	policy := g.branchPolicyFor(dir)
+	if policy.checkoutDefault {
+		if status, output := g.checkoutDefault(dir, remote); status != "" {
+			return status, output
+		}
+	}
+	if len(policy.protect) == 0 {
+		return "", nil
+	}
+
+	st, err := g.client.Status(dir)
+	if err != nil {
+		g.logger.Errorf("Error reading the branch of %s: %v", dir, err)
+		return statusFailed, []byte(err.Error() + "\n")
+	}
+	patterns, _ := compileBranches(policy.protect)
+	for _, re := range patterns {
+		if re.MatchString(st.Branch) {
+			return "", nil
+		}
+	}
+
+	g.logger.Infof("Skipping %s: branch %s is not one of the protected branches", dir, st.Branch)
+	return statusBranchCheck, []byte(fmt.Sprintf("checked-out branch %s does not match %s\n", st.Branch, strings.Join(policy.protect, ", ")))
+}
+
+// checkoutDefault switches dir to the default branch of remote unless it is
+// already checked out. Repositories with changes are left alone.
+func (g *GitPullCommand) checkoutDefault(dir, remote string) (string, []byte) {
+	branch, err := g.defaultBranch(dir, remote)
+	if err != nil {
+		g.logger.Errorf("Error finding the default branch of %s: %v", dir, err)
+		return statusFailed, []byte(err.Error() + "\n")
+	}
+
+	st, err := g.client.Status(dir)
+	if err != nil {
+		g.logger.Errorf("Error reading the branch of %s: %v", dir, err)
+		return statusFailed, []byte(err.Error() + "\n")
+	}
+	if st.Branch == branch {
+		return "", nil
+	}
+	if st.Dirty {
+		g.logger.Infof("Not switching %s to %s: the working tree has changes", dir, branch)
+		return statusBranchCheck, []byte(fmt.Sprintf("not switching from %s to the default branch %s: the working tree has changes\n", st.Branch, branch))
+	}
+
+	output, err := g.runner.Run(dir, "checkout", branch)
+	if err != nil {
+		g.logger.Errorf("Error switching %s to %s: %v", dir, branch, err)
+		return statusFailed, output
+	}
+	g.logger.Infof("Switched %s from %s to the default branch %s", dir, st.Branch, branch)
+	return "", nil
+}
+
+// defaultBranch reads the branch refs/remotes/<remote>/HEAD points to, asking
+// the remote when clone did not record it.
+func (g *GitPullCommand) defaultBranch(dir, remote string) (string, error) {
+	ref := "refs/remotes/" + remote + "/HEAD"
+	output, err := g.runner.Run(dir, "symbolic-ref", "--short", ref)
+	if err != nil {
+		if output, err := g.runner.Run(dir, "remote", "set-head", remote, "--auto"); err != nil {
+			return "", fmt.Errorf("%s: %s", ref, strings.TrimSpace(string(output)))
+		}
+		if output, err = g.runner.Run(dir, "symbolic-ref", "--short", ref); err != nil {
+			return "", fmt.Errorf("%s: %s", ref, strings.TrimSpace(string(output)))
+		}
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(output)), remote+"/"), nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// discoveryCache remembers the repositories found below each scanned root
+// so slow filesystems need not be walked on every run.
+type discoveryCache struct {
+	Roots map[string]cachedRoot `json:"roots"`
+}
+
+type cachedRoot struct {
+	Scanned time.Time    `json:"scanned"`
+	Repos   []cachedRepo `json:"repos"`
+}
+
+// cachedRepo is a repository path and the modification time of its git
+// directory when it was found.
+type cachedRepo struct {
+	Path  string    `json:"path"`
+	MTime time.Time `json:"mtime"`
+}
+
+func defaultCacheFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gitpuller", "repos.json")
+}
+
+func (g *GitPullCommand) addCacheFlags() {
+	flags := g.rootCmd.PersistentFlags()
+	flags.BoolVar(&g.cached, "cached", false, "Reuse the repositories found by the last scan instead of walking the directory")
+	flags.StringVar(&g.cacheFile, "cache-file", defaultCacheFile(), "File where discovered repositories are cached")
+}
+
+func (g *GitPullCommand) newScanCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "scan <dir>",
+		Short: "Walk a directory and refresh the repository discovery cache",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			count := 0
+			g.walk(args[0], func(string) { count++ })
+			fmt.Fprintf(cmd.OutOrStdout(), "Found %d repositories below %s\n", count, args[0])
+			return nil
+		},
+	}
+}
+
+func (g *GitPullCommand) readCache() (*discoveryCache, error) {
+	cache := &discoveryCache{Roots: map[string]cachedRoot{}}
+
+	data, err := os.ReadFile(g.cacheFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, cache); err != nil {
+		return nil, err
+	}
+	if cache.Roots == nil {
+		cache.Roots = map[string]cachedRoot{}
+	}
+	return cache, nil
+}
+
+func (g *GitPullCommand) writeCache(cache *discoveryCache) error {
+	if err := os.MkdirAll(filepath.Dir(g.cacheFile), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write through a temporary file so concurrent runs never read a
+	// partial cache.
+	tmp := g.cacheFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, g.cacheFile)
+}
+
+// cachedRepos returns the cached repositories below root that still exist,
+// or false when root was never scanned.
+func (g *GitPullCommand) cachedRepos(root string) ([]string, bool) {
+	cache, err := g.readCache()
+	if err != nil {
+		g.logger.Errorf("Error reading discovery cache: %v", err)
+		return nil, false
+	}
+
+	entry, ok := cache.Roots[root]
+	if !ok {
+		return nil, false
+	}
+
+	var repos []string
+	for _, repo := range entry.Repos {
+		if detectRepo(repo.Path) == kindNone {
+			g.logger.Infof("Dropping %s from the discovery cache: no longer a repository", repo.Path)
+			continue
+		}
+		repos = append(repos, repo.Path)
+	}
+
+	if len(repos) != len(entry.Repos) {
+		g.saveScan(root, repos)
+	}
+	return repos, true
+}
+
+// saveScan replaces the cached repositories of root.
+func (g *GitPullCommand) saveScan(root string, repos []string) {
+	if g.cacheFile == "" || g.sim.repos > 0 {
+		return
+	}
+
+	cache, err := g.readCache()
+	if err != nil {
+		g.logger.Errorf("Error reading discovery cache: %v", err)
+		return
+	}
+
+	entry := cachedRoot{Scanned: time.Now()}
+	for _, repo := range repos {
+		var mtime time.Time
+		if info, err := os.Stat(gitDirOf(repo)); err == nil {
+			mtime = info.ModTime()
+		}
+		entry.Repos = append(entry.Repos, cachedRepo{Path: repo, MTime: mtime})
+	}
+	cache.Roots[root] = entry
+
+	if err := g.writeCache(cache); err != nil {
+		g.logger.Errorf("Error writing discovery cache: %v", err)
+	}
+}
+
+// cacheKey identifies a root directory in the cache.
+func cacheKey(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func (g *GitPullCommand) addJobsFlags() {
+	g.rootCmd.PersistentFlags().IntVarP(&g.jobs, "jobs", "j", 0, "Maximum number of repositories pulled at once (0 means no limit)")
+}
+
+// handleSignals cancels g.ctx on the first SIGINT or SIGTERM so queued
+// repositories are cancelled and the partial summary still prints. Later
+// signals get the default behaviour and end the process at once. The
+// returned function stops watching for signals.
+func (g *GitPullCommand) handleSignals() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	g.ctx = ctx
+	g.cancel = cancel
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-sigs:
+			fmt.Fprintln(os.Stderr, "Interrupted: cancelling queued repositories; interrupt again to quit immediately")
+			signal.Reset(os.Interrupt, syscall.SIGTERM)
+			cancel()
+		case <-done:
+		}
+	}()
+
+	return func() {
+		close(done)
+		signal.Stop(sigs)
+		cancel()
+	}
+}
+
+// cancelled reports whether the run was interrupted.
+func (g *GitPullCommand) cancelled() bool {
+	return g.ctx.Err() != nil
+}
+
+// acquire waits for a free worker slot and reports false when the run is
+// cancelled first.
+func (g *GitPullCommand) acquire() bool {
+	if g.slots == nil {
+		return !g.cancelled()
+	}
+
+	select {
+	case g.slots <- struct{}{}:
+		// Both cases may be ready at once; cancellation wins.
+		if g.cancelled() {
+			<-g.slots
+			return false
+		}
+		return true
+	case <-g.ctx.Done():
+		return false
+	}
+}
+
+func (g *GitPullCommand) release() {
+	if g.slots != nil {
+		<-g.slots
+	}
+}
+
+// cancelQueued marks a repository that never got a worker as cancelled.
+func (g *GitPullCommand) cancelQueued(dir string) {
+	g.mu.Lock()
+	if g.statusOf(dir) == statusQueued {
+		g.updateStatus(dir, statusCancelled)
+	}
+	g.mu.Unlock()
+}
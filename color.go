@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// colorOptions controls ANSI colors in the summary, the streamed results and
+// the logs.
+type colorOptions struct {
+	mode    string
+	stream  string
+	enabled bool
+}
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+
+	streamLines  = "lines"
+	streamNDJSON = "ndjson"
+)
+
+// colorTheme maps the outcomes of the summary line to ANSI colors.
+var colorTheme = map[string]string{
+	msgOutcomeUpdated:   "\x1b[32m",
+	msgOutcomeFetched:   "\x1b[32m",
+	msgOutcomeUpToDate:  "\x1b[33m",
+	msgOutcomeSkipped:   "\x1b[33m",
+	msgOutcomeCancelled: "\x1b[33m",
+	msgOutcomeFailed:    "\x1b[31m",
+}
+
+const colorReset = "\x1b[0m"
+
+func (g *GitPullCommand) addColorFlags() {
+	g.rootCmd.PersistentFlags().StringVar(&g.color.mode, "color", colorAuto, "Color the output (options: auto, always, never); auto colors terminals unless NO_COLOR is set")
+
+	g.rootCmd.Flags().StringVar(&g.color.stream, "stream", "", "Print one line per repository as soon as its pull finishes (options: lines, ndjson)")
+}
+
+// resolve decides whether to color the output. An explicit --color always
+// wins over NO_COLOR.
+func (c *colorOptions) resolve() error {
+	switch c.mode {
+	case colorAlways:
+		c.enabled = true
+	case colorNever:
+		c.enabled = false
+	case colorAuto:
+		c.enabled = os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+	default:
+		return fmt.Errorf("invalid --color %q (options: auto, always, never)", c.mode)
+	}
+
+	switch c.stream {
+	case "", streamLines, streamNDJSON:
+	default:
+		return fmt.Errorf("invalid --stream %q (options: lines, ndjson)", c.stream)
+	}
+	return nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// paint colors text by the outcome of r.
+func (g *GitPullCommand) paint(r *repoResult, text string) string {
+	if !g.color.enabled {
+		return text
+	}
+	return colorTheme[outcome(r)] + text + colorReset
+}
+
+// startStream prints the results streamResult sends from a single
+// goroutine, so lines of concurrent pulls never interleave. The returned
+// function waits until every result is printed.
+func (g *GitPullCommand) startStream() func() {
+	if g.color.stream == "" {
+		return func() {}
+	}
+
+	results := make(chan repoResult, 64)
+	g.streamed = results
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		encoder := json.NewEncoder(os.Stdout)
+		for r := range results {
+			if g.color.stream == streamNDJSON {
+				if err := encoder.Encode(newServeResult(&r)); err != nil {
+					g.logger.Errorf("Error streaming the result of %s: %v", r.Dir, err)
+				}
+				continue
+			}
+			if g.view.template != nil {
+				fmt.Print(g.formatResult(&r))
+				continue
+			}
+			fmt.Println(g.paint(&r, g.streamLine(&r)))
+		}
+	}()
+
+	return func() {
+		g.streamed = nil
+		close(results)
+		<-done
+	}
+}
+
+// streamResult sends a copy of the finished result of dir to the stream.
+// Results hidden by --only or --quiet are left out.
+func (g *GitPullCommand) streamResult(dir string) {
+	if g.streamed == nil {
+		return
+	}
+
+	g.mu.Lock()
+	r := g.find(dir)
+	view := g.view
+	if view.quiet {
+		view.only = onlyFailed
+	}
+	if r == nil || !view.matches(r) {
+		g.mu.Unlock()
+		return
+	}
+	result := *r
+	g.mu.Unlock()
+
+	g.streamed <- result
+}
+
+// streamLine is the compact line of a result, e.g.
+// "Updated  src/app  1.2s  3 commits".
+func (g *GitPullCommand) streamLine(r *repoResult) string {
+	line := fmt.Sprintf("%-12s %s", g.msg(r.Status), r.Dir)
+	if r.Duration > 0 {
+		line += "  " + formatDuration(r.Duration)
+	}
+	if r.Commits > 0 {
+		line += fmt.Sprintf("  "+g.msg(msgStreamCommits), r.Commits)
+	}
+	return line
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// summaryColumn is a column of the summary table that --columns can select
+// by name.
+type summaryColumn struct {
+	name   string
+	header string
+	value  func(g *GitPullCommand, r *repoResult) string
+	// details marks columns filled in by recordDetails.
+	details bool
+}
+
+var summaryColumns = []summaryColumn{
+	{name: "dir", header: msgDirectory, value: func(_ *GitPullCommand, r *repoResult) string { return r.Dir }},
+	{name: "group", header: msgGroup, value: func(_ *GitPullCommand, r *repoResult) string { return r.Group }},
+	{name: "type", header: msgKind, value: func(g *GitPullCommand, r *repoResult) string { return g.msg(kindMessage(r.Kind)) }},
+	{name: "remote", header: msgRemote, value: func(_ *GitPullCommand, r *repoResult) string { return displayRemote(r) }},
+	{name: "status", header: msgStatus, value: func(g *GitPullCommand, r *repoResult) string { return g.msg(r.Status) }},
+	{name: "duration", header: msgDuration, value: func(_ *GitPullCommand, r *repoResult) string { return formatDuration(r.Duration) }},
+	{name: "commits", header: msgCommits, value: func(_ *GitPullCommand, r *repoResult) string { return strconv.Itoa(r.Commits) }},
+	{name: "files", header: msgFiles, value: func(_ *GitPullCommand, r *repoResult) string { return strconv.Itoa(r.Files) }},
+	{name: "received", header: msgReceived, value: func(_ *GitPullCommand, r *repoResult) string { return formatBytes(r.Received) }},
+	{name: "objects", header: msgObjects, value: func(_ *GitPullCommand, r *repoResult) string { return strconv.Itoa(r.Objects) }},
+	{name: "pruned", header: msgPruned, value: func(_ *GitPullCommand, r *repoResult) string { return strconv.Itoa(r.Pruned) }},
+	{name: "git-size", header: msgGitSize, value: func(_ *GitPullCommand, r *repoResult) string { return formatBytes(r.GitSize) }},
+	{name: "tree-size", header: msgTreeSize, value: func(_ *GitPullCommand, r *repoResult) string { return formatBytes(r.TreeSize) }},
+	{name: "branch", header: msgBranch, details: true, value: func(_ *GitPullCommand, r *repoResult) string { return r.Branch }},
+	{name: "ahead", header: msgAhead, details: true, value: func(_ *GitPullCommand, r *repoResult) string { return formatCount(r.Ahead, r.Tracking) }},
+	{name: "behind", header: msgBehind, details: true, value: func(_ *GitPullCommand, r *repoResult) string { return formatCount(r.Behind, r.Tracking) }},
+	{name: "author", header: msgAuthor, details: true, value: func(_ *GitPullCommand, r *repoResult) string { return r.Author }},
+	{name: "date", header: msgDate, details: true, value: func(_ *GitPullCommand, r *repoResult) string { return formatDate(r.CommitDate) }},
+	{name: "archived", header: msgArchived, value: formatArchived},
+	{name: "default-branch", header: msgDefaultBranch, value: func(_ *GitPullCommand, r *repoResult) string { return r.DefaultBranch }},
+	{name: "open-prs", header: msgOpenPRs, value: func(_ *GitPullCommand, r *repoResult) string { return formatCount(r.OpenPRs, r.Forge) }},
+	{name: "stashes", header: msgStashes, details: true, value: func(_ *GitPullCommand, r *repoResult) string { return strconv.Itoa(r.Stashes) }},
+}
+
+func columnNames() string {
+	names := make([]string, len(summaryColumns))
+	for i, c := range summaryColumns {
+		names[i] = c.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// lookupColumns resolves --columns names in the given order.
+func lookupColumns(names []string) ([]summaryColumn, error) {
+	columns := make([]summaryColumn, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, c := range summaryColumns {
+			if c.name == name {
+				columns = append(columns, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid --columns %q (options: %s)", name, columnNames())
+		}
+	}
+	return columns, nil
+}
+
+// defaultColumns chooses the columns shown without --columns. Optional
+// columns only appear when they carry information for this run: groups for
+// group runs, the type when bare repositories or linked worktrees are part
+// of the run and transfer sizes, which git only reports for larger fetches,
+// when something was received, and API metadata when it was looked up.
+func (g *GitPullCommand) defaultColumns(results []*repoResult) []string {
+	showGroup, showKind, showReceived, showForge := false, false, false, false
+	for _, r := range results {
+		if r.Forge {
+			showForge = true
+		}
+		if r.Group != "" {
+			showGroup = true
+		}
+		if r.Kind == kindBare || r.Kind == kindWorktree {
+			showKind = true
+		}
+		if r.Received > 0 {
+			showReceived = true
+		}
+	}
+
+	names := []string{"dir"}
+	if showGroup {
+		names = append(names, "group")
+	}
+	if showKind {
+		names = append(names, "type")
+	}
+	names = append(names, "remote", "status", "duration", "commits", "files")
+	if showReceived {
+		names = append(names, "received")
+	}
+	if showForge {
+		names = append(names, "default-branch", "open-prs")
+	}
+	if g.prune.remote {
+		names = append(names, "pruned")
+	}
+	if g.sizes {
+		names = append(names, "git-size", "tree-size")
+	}
+	return names
+}
+
+// wantsDetails reports whether the selected columns need recordDetails.
+func (v viewOptions) wantsDetails() bool {
+	columns, _ := lookupColumns(v.columns)
+	for _, c := range columns {
+		if c.details {
+			return true
+		}
+	}
+	return false
+}
+
+// recordDetails looks up the branch, upstream distance, last commit and
+// stash count of dir for the summary.
+func (g *GitPullCommand) recordDetails(dir string) {
+	var branch, author string
+	var ahead, behind int
+	var date time.Time
+	tracking := false
+
+	if output, err := g.runner.Run(dir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil {
+		branch = strings.TrimSpace(string(output))
+	}
+	if output, err := g.runner.Run(dir, "rev-list", "--left-right", "--count", "HEAD...@{upstream}"); err == nil {
+		if fields := strings.Fields(string(output)); len(fields) == 2 {
+			ahead, _ = strconv.Atoi(fields[0])
+			behind, _ = strconv.Atoi(fields[1])
+			tracking = true
+		}
+	}
+	if output, err := g.runner.Run(dir, "log", "-1", "--format=%an%x00%ct"); err == nil {
+		if name, stamp, ok := strings.Cut(strings.TrimSpace(string(output)), "\x00"); ok {
+			author = name
+			if seconds, err := strconv.ParseInt(stamp, 10, 64); err == nil {
+				date = time.Unix(seconds, 0)
+			}
+		}
+	}
+
+	stashes := g.countStashes(dir)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if r := g.find(dir); r != nil {
+		r.Stashes = stashes
+		r.Branch = branch
+		r.Ahead = ahead
+		r.Behind = behind
+		r.Tracking = tracking
+		r.Author = author
+		r.CommitDate = date
+	}
+}
+
+// formatCount leaves counts blank that could not be determined.
+func formatCount(n int, known bool) string {
+	if !known {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04")
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigPath follows the XDG base directory layout.
+func defaultConfigPath() string {
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		base = filepath.Join(home, ".config")
+	}
+	return filepath.Join(base, "gitpuller", "config.yaml")
+}
+
+// configReposKey holds per-path repository settings and configGroupsKey
+// named repository groups rather than flags.
+const (
+	configReposKey  = "repos"
+	configGroupsKey = "groups"
+)
+
+// loadConfig applies the config file to every flag of cmd that was not set
+// on the command line. Top-level keys are flag names, for example
+//
+//	post-hook: make deps
+//	sort: dir
+//
+// except for repos, which lists settings for repositories below a path, and
+// groups, which defines the sets run by the group command.
+func (g *GitPullCommand) loadConfig(cmd *cobra.Command) error {
+	g.fromConfig = map[string]bool{}
+	path := g.configPath
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !cmd.Flags().Changed("config") {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	var repos struct {
+		Repos []repoOverride `yaml:"repos"`
+	}
+	if err := yaml.Unmarshal(data, &repos); err != nil {
+		return fmt.Errorf("%s: %s: %v", path, configReposKey, err)
+	}
+	g.settings.perPath = repos.Repos
+
+	var groups struct {
+		Groups map[string]repoGroup `yaml:"groups"`
+	}
+	if err := yaml.Unmarshal(data, &groups); err != nil {
+		return fmt.Errorf("%s: %s: %v", path, configGroupsKey, err)
+	}
+	g.groups = groups.Groups
+
+	for key, value := range values {
+		if key == configReposKey || key == configGroupsKey {
+			continue
+		}
+		flag := cmd.Flags().Lookup(key)
+		if flag == nil {
+			g.logger.Debugf("Ignoring config key %q: no such flag for %s", key, cmd.Name())
+			continue
+		}
+		if flag.Changed {
+			continue
+		}
+		if err := setFlag(flag, value); err != nil {
+			return fmt.Errorf("%s: %s: %v", path, key, err)
+		}
+		g.fromConfig[key] = true
+	}
+	return nil
+}
+
+// setFlag assigns a decoded YAML value to a flag, accepting lists for
+// slice flags. Slice flags are replaced rather than appended to, so a value
+// set earlier from the config file can be overridden.
+func setFlag(flag *pflag.Flag, value interface{}) error {
+	list, isList := value.([]interface{})
+	if !isList {
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			return slice.Replace([]string{fmt.Sprint(value)})
+		}
+		return flag.Value.Set(fmt.Sprint(value))
+	}
+
+	items := make([]string, len(list))
+	for i, item := range list {
+		items[i] = fmt.Sprint(item)
+	}
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		return slice.Replace(items)
+	}
+	return flag.Value.Set(strings.Join(items, ","))
+}
@@ -0,0 +1,35 @@
+package main
+
+import "fmt"
+
+const (
+	onConflictAbort = "abort"
+	onConflictKeep  = "keep"
+)
+
+func validateOnConflict(value string) error {
+	switch value {
+	case onConflictAbort, onConflictKeep:
+		return nil
+	}
+	return fmt.Errorf("invalid --on-conflict %q (options: abort, keep)", value)
+}
+
+// handleConflict deals with a merge or rebase that stopped on conflicts in
+// dir. By default it is aborted so the working tree is back where it was and
+// the next run can try again; --on-conflict keep leaves it for resolving by
+// hand.
+func (g *GitPullCommand) handleConflict(dir, remote string, output []byte) (string, []byte) {
+	if g.onConflict == onConflictKeep {
+		g.logger.Errorf("Conflicts in %s left for manual resolution", dir)
+		return statusConflict, output
+	}
+
+	g.logger.Errorf("Conflicts in %s; aborting", dir)
+	aborted, err := g.client.AbortIntegrate(dir, remote)
+	output = append(output, aborted...)
+	if err != nil {
+		g.logger.Errorf("Error aborting conflicted integration in %s: %v", dir, err)
+	}
+	return statusConflict, output
+}
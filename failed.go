@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func defaultFailedFile() string {
+	if dir := defaultStateDir(); dir != "" {
+		return filepath.Join(dir, "failed")
+	}
+	return ""
+}
+
+func (g *GitPullCommand) addFailedFlags() {
+	g.rootCmd.Flags().StringVar(&g.failedFile, "failed-file", defaultFailedFile(), "File listing the repositories that failed in the last run, one absolute path per line, for gitpull retry-failed")
+}
+
+// saveFailed replaces the failed file with the repositories that failed
+// this run. It is replaced atomically so scripts never read half a list.
+func (g *GitPullCommand) saveFailed() {
+	if g.failedFile == "" || g.sim.repos > 0 {
+		return
+	}
+
+	var lines strings.Builder
+	g.mu.Lock()
+	for _, r := range g.summary {
+		if isFailure(r.Status) {
+			lines.WriteString(cacheKey(r.Dir) + "\n")
+		}
+	}
+	g.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(g.failedFile), 0o755); err != nil {
+		g.logger.Errorf("Error saving failed repositories: %v", err)
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(g.failedFile), ".gitpull-failed-")
+	if err != nil {
+		g.logger.Errorf("Error saving failed repositories: %v", err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(lines.String()); err != nil {
+		tmp.Close()
+		g.logger.Errorf("Error saving failed repositories: %v", err)
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		g.logger.Errorf("Error saving failed repositories: %v", err)
+		return
+	}
+	if err := os.Rename(tmp.Name(), g.failedFile); err != nil {
+		g.logger.Errorf("Error saving failed repositories: %v", err)
+	}
+}
+
+// readFailed returns the repositories listed in path. A missing file lists
+// none.
+func readFailed(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var dirs []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if dir := strings.TrimSpace(scanner.Text()); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs, scanner.Err()
+}
+
+func (g *GitPullCommand) newRetryFailedCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "retry-failed",
+		Short: "Pull only the repositories that failed in the last run",
+		Long: "Pull only the repositories listed in --failed-file, without scanning for others. " +
+			"The file is rewritten with what still fails, so repeated retries work through the list.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if g.sim.repos > 0 {
+				g.logger.Errorf("Error: --simulate cannot be combined with retry-failed")
+				os.Exit(1)
+			}
+			dirs, err := readFailed(g.failedFile)
+			if err != nil {
+				g.logger.Errorf("Error reading failed repositories: %v", err)
+				os.Exit(1)
+			}
+			if len(dirs) == 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), g.msg(msgNoFailed)+"\n", g.failedFile)
+				return
+			}
+			g.execute("retry-failed", "retry-failed", func() { g.pullDirs(dirs) })
+		},
+	}
+
+	// A retry takes the same options as the run that failed.
+	cmd.Flags().AddFlagSet(g.rootCmd.LocalNonPersistentFlags())
+	return cmd
+}
+
+// pullDirs pulls the given repositories and waits for all pulls to finish.
+// Asking for them by name overrides the quarantine.
+func (g *GitPullCommand) pullDirs(dirs []string) {
+	for _, dir := range dirs {
+		if !g.addResult(dir, statusQueued) {
+			continue
+		}
+		g.mu.Lock()
+		delete(g.quarantined, dir)
+		g.mu.Unlock()
+		g.queue(dir, g.pullRepository)
+	}
+	g.confirm()
+
+	g.wait()
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// fetchOptions limit how much history and data a fetch downloads, for
+// large repositories where full history is not needed.
+type fetchOptions struct {
+	depth  int
+	filter string
+	tags   bool
+}
+
+func (g *GitPullCommand) addFetchFlags() {
+	flags := g.rootCmd.PersistentFlags()
+	flags.IntVar(&g.fetch.depth, "depth", 0, "Limit fetched history to this many commits per branch; must exceed how far repositories are behind (0 fetches everything)")
+	flags.StringVar(&g.fetch.filter, "filter", "", "Partial fetch filter passed to git, e.g. blob:none")
+	flags.BoolVar(&g.fetch.tags, "tags", false, "Fetch all tags from the remote, not only those on fetched branches")
+}
+
+func (o fetchOptions) validate(backend string) error {
+	if o.depth < 0 {
+		return fmt.Errorf("--depth must not be negative")
+	}
+	if o.filter != "" && backend != backendExec {
+		return fmt.Errorf("--filter requires the %s backend", backendExec)
+	}
+	return nil
+}
+
+// args returns the git fetch options for o.
+func (o fetchOptions) args() []string {
+	var args []string
+	if o.depth > 0 {
+		args = append(args, "--depth="+strconv.Itoa(o.depth))
+	}
+	if o.filter != "" {
+		args = append(args, "--filter="+o.filter)
+	}
+	if o.tags {
+		args = append(args, "--tags")
+	}
+	return args
+}
+
+// receivedPattern matches git's final transfer progress line, e.g.
+// "Receiving objects: 100% (3/3), 293.29 KiB | 73.32 MiB/s, done." Older
+// versions of git leave the size out when unpacking.
+var receivedPattern = regexp.MustCompile(`(?m)(?:Receiving|Unpacking) objects: 100% \((\d+)/\d+\)(?:, ([\d.]+) (bytes|KiB|MiB|GiB))?`)
+
+var byteUnits = map[string]float64{
+	"bytes": 1,
+	"KiB":   1 << 10,
+	"MiB":   1 << 20,
+	"GiB":   1 << 30,
+}
+
+// parseReceived returns the number of bytes and objects a fetch downloaded
+// according to its progress output, or 0 when git did not report them. Git
+// omits the line for small fetches that finish before progress is shown.
+func parseReceived(output []byte) (int64, int) {
+	var total int64
+	objects := 0
+	for _, m := range receivedPattern.FindAllSubmatch(output, -1) {
+		if n, err := strconv.Atoi(string(m[1])); err == nil {
+			objects += n
+		}
+		if size, err := strconv.ParseFloat(string(m[2]), 64); err == nil {
+			total += int64(size * byteUnits[string(m[3])])
+		}
+	}
+	return total, objects
+}
+
+// recordReceived stores how much data the fetch of dir downloaded and adds
+// it to the run's totals.
+func (g *GitPullCommand) recordReceived(dir string, output []byte) {
+	received, objects := parseReceived(output)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.updateReceived(dir, received, objects)
+	g.addTransfer(dir, received, objects)
+}
+
+// formatBytes renders sizes the way git's progress output does.
+func formatBytes(n int64) string {
+	switch {
+	case n == 0:
+		return ""
+	case n < 1<<10:
+		return fmt.Sprintf("%d bytes", n)
+	case n < 1<<20:
+		return fmt.Sprintf("%.2f KiB", float64(n)/(1<<10))
+	case n < 1<<30:
+		return fmt.Sprintf("%.2f MiB", float64(n)/(1<<20))
+	}
+	return fmt.Sprintf("%.2f GiB", float64(n)/(1<<30))
+}
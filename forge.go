@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// forgeOptions control the lookup of repository metadata from the GitHub or
+// GitLab API for remotes whose host has a token (GITPULL_TOKEN_<HOST> or
+// --credentials-file).
+type forgeOptions struct {
+	skipArchived bool
+	timeout      time.Duration
+}
+
+// forgeInfo is the metadata shown in the summary.
+type forgeInfo struct {
+	archived      bool
+	defaultBranch string
+	openPRs       int
+}
+
+func (g *GitPullCommand) addForgeFlags() {
+	flags := g.rootCmd.PersistentFlags()
+	flags.BoolVar(&g.forge.skipArchived, "skip-archived", true, "Skip repositories whose GitHub or GitLab project is archived; needs a token for the host")
+	flags.DurationVar(&g.forge.timeout, "api-timeout", 10*time.Second, "How long GitHub and GitLab API requests may take")
+}
+
+// checkForge looks up the project of remoteURL and records its metadata. It
+// returns the status to finish the pull with when the project is archived,
+// or "" when the pull may go ahead. API errors never fail a pull.
+func (g *GitPullCommand) checkForge(dir, remoteURL string) (string, []byte) {
+	host, path := splitRemoteURL(remoteURL)
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || !strings.Contains(path, "/") || g.creds == nil {
+		return "", nil
+	}
+	cred, ok := g.creds.lookup(host)
+	if !ok {
+		return "", nil
+	}
+
+	var info forgeInfo
+	var err error
+	switch {
+	case strings.Contains(host, "github"):
+		info, err = g.githubInfo(host, path, cred.token)
+	case strings.Contains(host, "gitlab"):
+		info, err = g.gitlabInfo(host, path, cred.token)
+	default:
+		return "", nil
+	}
+	if err != nil {
+		g.logger.Warnf("Could not look up %s/%s: %v", host, path, err)
+		return "", nil
+	}
+
+	g.mu.Lock()
+	if r := g.find(dir); r != nil {
+		r.Forge = true
+		r.Archived = info.archived
+		r.DefaultBranch = info.defaultBranch
+		r.OpenPRs = info.openPRs
+	}
+	g.mu.Unlock()
+
+	if info.archived && g.forge.skipArchived {
+		g.logger.Infof("Skipping %s: %s/%s is archived", dir, host, path)
+		return statusArchived, []byte(fmt.Sprintf("%s/%s is archived\n", host, path))
+	}
+	return "", nil
+}
+
+// githubInfo asks the GitHub API, or that of a GitHub Enterprise host, for
+// a repository and counts its open pull requests.
+func (g *GitPullCommand) githubInfo(host, path, token string) (forgeInfo, error) {
+	api := "https://" + host + "/api/v3"
+	if host == "github.com" {
+		api = "https://api.github.com"
+	}
+	header := http.Header{"Authorization": {"Bearer " + token}, "Accept": {"application/vnd.github+json"}}
+
+	var repo struct {
+		Archived      bool   `json:"archived"`
+		DefaultBranch string `json:"default_branch"`
+	}
+	if _, err := g.getJSON(api+"/repos/"+path, header, &repo); err != nil {
+		return forgeInfo{}, err
+	}
+
+	// A single pull request per page makes the last page of the Link
+	// header the count.
+	var pulls []json.RawMessage
+	response, err := g.getJSON(api+"/repos/"+path+"/pulls?state=open&per_page=1", header, &pulls)
+	if err != nil {
+		return forgeInfo{}, err
+	}
+	count := len(pulls)
+	if m := lastPagePattern.FindStringSubmatch(response.Get("Link")); m != nil {
+		count, _ = strconv.Atoi(m[1])
+	}
+	return forgeInfo{archived: repo.Archived, defaultBranch: repo.DefaultBranch, openPRs: count}, nil
+}
+
+var lastPagePattern = regexp.MustCompile(`[?&]page=(\d+)[^>]*>; rel="last"`)
+
+// gitlabInfo asks the GitLab API for a project and counts its open merge
+// requests.
+func (g *GitPullCommand) gitlabInfo(host, path, token string) (forgeInfo, error) {
+	api := "https://" + host + "/api/v4/projects/" + url.PathEscape(path)
+	header := http.Header{"PRIVATE-TOKEN": {token}}
+
+	var project struct {
+		Archived      bool   `json:"archived"`
+		DefaultBranch string `json:"default_branch"`
+	}
+	if _, err := g.getJSON(api, header, &project); err != nil {
+		return forgeInfo{}, err
+	}
+
+	var requests []json.RawMessage
+	response, err := g.getJSON(api+"/merge_requests?state=opened&per_page=1", header, &requests)
+	if err != nil {
+		return forgeInfo{}, err
+	}
+	count := len(requests)
+	if total, err := strconv.Atoi(response.Get("X-Total")); err == nil {
+		count = total
+	}
+	return forgeInfo{archived: project.Archived, defaultBranch: project.DefaultBranch, openPRs: count}, nil
+}
+
+// getJSON decodes the response to a GET request into v and returns its
+// headers.
+func (g *GitPullCommand) getJSON(rawURL string, header http.Header, v interface{}) (http.Header, error) {
+	req, err := http.NewRequestWithContext(g.ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	req.Header.Set("User-Agent", "gitpull")
+
+	client := http.Client{Timeout: g.forge.timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", req.URL.Redacted(), resp.Status)
+	}
+	return resp.Header, json.NewDecoder(resp.Body).Decode(v)
+}
+
+// formatArchived leaves the column blank for repositories not looked up.
+func formatArchived(g *GitPullCommand, r *repoResult) string {
+	switch {
+	case !r.Forge:
+		return ""
+	case r.Archived:
+		return g.msg(msgYes)
+	}
+	return g.msg(msgNo)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// parseFormat compiles --format, a text/template executed for every result
+// of the summary and of --stream lines, e.g.
+// '{{.Dir}} {{msg .Status}} {{duration .Duration}}'.
+func (g *GitPullCommand) parseFormat() error {
+	if g.view.format == "" {
+		return nil
+	}
+	if g.color.stream == streamNDJSON {
+		return fmt.Errorf("--format cannot be combined with --stream %s", streamNDJSON)
+	}
+
+	funcs := template.FuncMap{
+		"msg":      g.msg,
+		"duration": formatDuration,
+		"bytes":    formatBytes,
+		"date":     formatDate,
+		"remote":   displayRemote,
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}
+	tmpl, err := template.New("format").Funcs(funcs).Parse(g.view.format)
+	if err != nil {
+		return fmt.Errorf("invalid --format: %v", err)
+	}
+	// Catch unknown fields before the run rather than once per result.
+	if err := tmpl.Execute(io.Discard, &repoResult{}); err != nil {
+		return fmt.Errorf("invalid --format: %v", err)
+	}
+	g.view.template = tmpl
+	return nil
+}
+
+// formatResult renders r with --format, ending in a newline.
+func (g *GitPullCommand) formatResult(r *repoResult) string {
+	var buf bytes.Buffer
+	if err := g.view.template.Execute(&buf, r); err != nil {
+		g.logger.Errorf("Error formatting the result of %s: %v", r.Dir, err)
+		return ""
+	}
+	out := buf.String()
+	if !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	return out
+}
@@ -1,54 +1,311 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
-	"github.com/olekukonko/tablewriter"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
+const (
+	statusPending  = "Pending"
+	statusQueued   = "Queued"
+	statusPulling  = "Pulling"
+	statusUpdated  = "Updated"
+	statusUpToDate = "Up-to-date"
+	statusFailed   = "Failed"
+	statusSkipped  = "Skipped"
+
+	statusFetchedNotMerged = "FetchedNotMerged"
+	statusAuthRequired     = "Auth required"
+	statusQuarantined      = "Quarantined"
+	statusHookFailed       = "Hook failed"
+	statusFetched          = "Fetched"
+	statusConflict         = "Conflict"
+	statusLFSFailed        = "LFS failed"
+	statusCancelled        = "Cancelled"
+	statusResumed          = "Already pulled"
+	statusUnreachable      = "Remote unreachable"
+	statusBranchCheck      = "Protected branch check"
+	statusPinned           = "Pinned (up to date)"
+	statusPinnedMoved      = "Pinned (moved)"
+	statusHostKey          = "Host key verification required"
+	statusArchived         = "Archived"
+	statusOwnership        = "Ownership mismatch"
+
+	statusPushing       = "Pushing"
+	statusPushed        = "Pushed"
+	statusWouldPush     = "Would push"
+	statusNothingToPush = "Nothing to push"
+	statusNoUpstream    = "No upstream"
+
+	statusMaintaining = "Maintaining"
+	statusMaintained  = "Maintained"
+	statusCorrupt     = "Corrupt"
+
+	statusFetching     = "Fetching"
+	statusCloned       = "Cloned"
+	statusNewRelease   = "New release"
+	statusNoNewRelease = "No new release"
+
+	statusRepairing   = "Repairing"
+	statusRepaired    = "Repaired"
+	statusHealthy     = "Healthy"
+	statusNeedsRepair = "Needs repair"
+)
+
+// repoResult tracks the state of a single repository during a run.
+type repoResult struct {
+	Dir        string
+	Key        string
+	Group      string
+	Kind       string
+	Remote     string
+	RemoteName string
+	Status     string
+	Received   int64
+	Objects    int
+	Output     string
+	Commits    int
+	Files      int
+	Pruned     int
+	Log        []string
+	Size       int64
+	Reclaimed  int64
+	GitSize    int64
+	TreeSize   int64
+	TagBefore  string
+	TagAfter   string
+
+	// Details for --columns, filled in by recordDetails.
+	Branch     string
+	Tracking   bool
+	Ahead      int
+	Behind     int
+	Author     string
+	CommitDate time.Time
+	Stashes    int
+
+	// Project metadata from the GitHub or GitLab API, see checkForge.
+	Forge         bool
+	Archived      bool
+	DefaultBranch string
+	OpenPRs       int
+
+	Started  time.Time
+	Duration time.Duration
+}
+
 type GitPullCommand struct {
-	rootCmd    *cobra.Command
-	debug      bool
-	logLevel   string
-	logger     *logrus.Logger
-	summary    [][]string
-	wg         sync.WaitGroup
-	mu         sync.Mutex
+	rootCmd  *cobra.Command
+	debug    bool
+	logLevel string
+	lang     string
+	backend  string
+	tui      bool
+
+	fetchOnlyOnConflict bool
+	onConflict          string
+	remote              remoteOptions
+	fetch               fetchOptions
+	settings            repoSettings
+	lfs                 bool
+	popStash            bool
+	color               colorOptions
+	branches            branchPolicy
+	transfer            transferOptions
+	interactive         interactiveOptions
+	strategy            strategyOptions
+	streamed            chan repoResult
+	prune               pruneOptions
+	auth                authOptions
+	quarantine          quarantineOptions
+	historyDir          string
+	failedFile          string
+	noHistory           bool
+	view                viewOptions
+	hooks               hookOptions
+	notify              notifyOptions
+	metrics             metricsOptions
+	reports             []string
+	configPath          string
+	fromConfig          map[string]bool
+	groups              map[string]repoGroup
+	cached              bool
+	cacheFile           string
+	resume              bool
+	stateFile           string
+	sizes               bool
+	verboseGit          bool
+	safeDirectory       safeDirectoryOptions
+	lock                lockOptions
+
+	jobs     int
+	walkJobs int
+	nested   bool
+	hosts    hostOptions
+	hostKeys hostKeyOptions
+	forge    forgeOptions
+	creds    *credentialStore
+	sim      simOptions
+	ctx      context.Context
+	cancel   context.CancelFunc
+	slots    chan struct{}
+	logger   *logrus.Logger
+	runner   gitRunner
+	client   GitClient
+	summary  []*repoResult
+	// byDir and byKey index summary by Dir and Key.
+	byDir map[string]*repoResult
+	byKey map[string]*repoResult
+	// noGit is why the git binary is missing, which only the go-git
+	// backend tolerates.
+	noGit error
+
+	lastRun     time.Time
+	quarantined map[string]bool
+	resumed     map[string]bool
+	state       *runState
+	repoLocks   map[string]*sync.Mutex
+	wg          sync.WaitGroup
+	mu          sync.Mutex
 }
 
 func NewGitPullCommand() *GitPullCommand {
 	g := &GitPullCommand{
+		ctx:     context.Background(),
 		logger:  logrus.New(),
-		summary: [][]string{},
+		summary: []*repoResult{},
+		byDir:   map[string]*repoResult{},
+		byKey:   map[string]*repoResult{},
 	}
 
 	g.rootCmd = &cobra.Command{
-		Use:   "gitpull",
-		Short: "Traverse directories and perform git pull",
-		Args:  cobra.ExactArgs(1),
-		Run:   g.run,
+		Use:               "gitpull",
+		Short:             "Traverse directories and perform git pull",
+		Args:              g.validateArgs,
+		PersistentPreRunE: g.setup,
+		Run:               g.run,
+		SilenceUsage:      true,
 	}
 
+	g.rootCmd.PersistentFlags().StringVar(&g.configPath, "config", defaultConfigPath(), "Config file whose keys set defaults for flags")
 	g.rootCmd.PersistentFlags().BoolVar(&g.debug, "debug", false, "Enable debug logging")
 	g.rootCmd.PersistentFlags().StringVar(&g.logLevel, "log-level", "error", "Logging level (options: debug, info, warning, error, fatal, panic)")
-	g.rootCmd.ParseFlags(os.Args)
+	g.rootCmd.PersistentFlags().StringVar(&g.lang, "lang", "", "Language for statuses and reports (default from LANG, falling back to en)")
+	g.rootCmd.PersistentFlags().StringVar(&g.backend, "backend", backendExec, "Git implementation (options: exec, gogit)")
+	g.rootCmd.Flags().BoolVar(&g.tui, "tui", false, "Show an interactive view with live repository statuses")
+	g.rootCmd.Flags().BoolVar(&g.fetchOnlyOnConflict, "fetch-only-on-conflict", false, "Leave repositories fetched but not merged when integrating would conflict")
+	g.rootCmd.PersistentFlags().StringVar(&g.onConflict, "on-conflict", onConflictAbort, "What to do when a merge or rebase stops on conflicts (options: abort, keep)")
+	g.addRemoteFlags()
+	g.addFetchFlags()
+	g.addSettingsFlags()
+	g.addLFSFlags()
+	g.addStashFlags()
+	g.addColorFlags()
+	g.addBranchFlags()
+	g.addTransferFlags()
+	g.addInteractiveFlags()
+	g.addStrategyFlags()
+	g.addFailedFlags()
+	g.addAuthFlags()
+	g.addHostKeyFlags()
+	g.addForgeFlags()
+	g.addLiveFlags()
+	g.addOwnershipFlags()
+	g.addPruneFlags()
+	g.addHookFlags()
+	g.addCacheFlags()
+	g.addQuarantineFlags()
+	g.addSummaryFlags()
+	g.addNotifyFlags()
+	g.addMetricsFlags()
+	g.addReportFlags()
+	g.addStateFlags()
+	g.addLockFlags()
+	g.addSizesFlags()
+	g.addJobsFlags()
+	g.addHostFlags()
+	g.addWalkFlags()
+	g.rootCmd.PersistentFlags().StringVar(&g.historyDir, "history-dir", defaultHistoryDir(), "Directory where run results are recorded")
+	g.rootCmd.Flags().BoolVar(&g.noHistory, "no-history", false, "Do not record this run in the history")
+	g.addSimulationFlags()
+	g.rootCmd.AddCommand(g.newFixtureCommand())
+	g.rootCmd.AddCommand(g.newPushCommand())
+	g.rootCmd.AddCommand(g.newMaintainCommand())
+	g.rootCmd.AddCommand(g.newRepairCommand())
+	g.rootCmd.AddCommand(g.newReleasesCommand())
+	g.rootCmd.AddCommand(g.newMirrorCommand())
+	g.rootCmd.AddCommand(g.newActivityCommand())
+	g.rootCmd.AddCommand(g.newStashesCommand())
+	g.rootCmd.AddCommand(g.newHistoryCommand())
+	g.rootCmd.AddCommand(g.newDiffLastCommand())
+	g.rootCmd.AddCommand(g.newServeCommand())
+	g.rootCmd.AddCommand(g.newShellCommand())
+	g.rootCmd.AddCommand(g.newScanCommand())
+	g.rootCmd.AddCommand(g.newGroupCommand())
+	g.rootCmd.AddCommand(g.newRetryFailedCommand())
 
+	return g
+}
+
+// setup applies the config file and configures logging and language once
+// the command line has been parsed.
+func (g *GitPullCommand) setup(cmd *cobra.Command, args []string) error {
 	g.setupLogger()
+	if err := g.loadConfig(cmd); err != nil {
+		return fmt.Errorf("loading config: %v", err)
+	}
+	return g.configure()
+}
 
-	return g
+// configure applies the settings that flags from the config file or a
+// group may have changed.
+func (g *GitPullCommand) configure() error {
+	if err := g.color.resolve(); err != nil {
+		return err
+	}
+	g.setupLogger()
+	g.setupLang()
+
+	if g.jobs < 0 {
+		return fmt.Errorf("--jobs must not be negative")
+	}
+	if g.jobs > 0 {
+		g.slots = make(chan struct{}, g.jobs)
+	}
+	if g.walkJobs < 1 {
+		return fmt.Errorf("--walk-jobs must be at least 1")
+	}
+	if err := g.hosts.validate(); err != nil {
+		return err
+	}
+	return g.validateBranchPolicy()
+}
+
+func (g *GitPullCommand) setupLang() {
+	lang, err := resolveLang(g.lang)
+	if err != nil {
+		fmt.Printf("Invalid language: %v\n", err)
+		os.Exit(1)
+	}
+
+	g.lang = lang
 }
 
 func (g *GitPullCommand) setupLogger() {
 	g.logger.SetOutput(os.Stdout)
 	g.logger.SetFormatter(&logrus.TextFormatter{
 		DisableTimestamp: true,
+		ForceColors:      g.color.enabled,
+		DisableColors:    !g.color.enabled,
 	})
 
 	level, err := logrus.ParseLevel(g.logLevel)
@@ -59,116 +316,685 @@ func (g *GitPullCommand) setupLogger() {
 
 	if g.debug {
 		level = logrus.DebugLevel
+	} else if g.view.quiet {
+		level = logrus.FatalLevel
 	}
 
 	g.logger.SetLevel(level)
 }
 
+// validateArgs requires a directory unless a simulation fabricates one.
+func (g *GitPullCommand) validateArgs(cmd *cobra.Command, args []string) error {
+	if g.sim.repos > 0 {
+		return cobra.MaximumNArgs(0)(cmd, args)
+	}
+	return cobra.ExactArgs(1)(cmd, args)
+}
+
 func (g *GitPullCommand) run(cmd *cobra.Command, args []string) {
+	if g.sim.repos > 0 {
+		dir, cleanup, err := g.startSimulation()
+		if err != nil {
+			g.logger.Errorf("Error preparing simulation: %v", err)
+			os.Exit(1)
+		}
+		defer cleanup()
+		args = []string{dir}
+	}
+
 	dir := args[0]
+	g.execute(dir, cacheKey(dir), func() { g.pullAll(dir) })
+}
+
+// execute performs a complete run: pull calls pullRepository for every
+// repository of the run, root names the run in reports and history and key
+// identifies it for --resume.
+func (g *GitPullCommand) execute(root, key string, pull func()) {
+	stopSignals := g.handleSignals()
+	defer stopSignals()
+
+	if err := g.setupClient(); err != nil {
+		g.logger.Errorf("Error: %v", err)
+		os.Exit(1)
+	}
 
-	err := filepath.Walk(dir, g.visit)
+	if err := g.view.validate(); err != nil {
+		g.logger.Errorf("Error: %v", err)
+		os.Exit(1)
+	}
+
+	if err := validateOnConflict(g.onConflict); err != nil {
+		g.logger.Errorf("Error: %v", err)
+		os.Exit(1)
+	}
+
+	if err := g.remote.validate(); err != nil {
+		g.logger.Errorf("Error: %v", err)
+		os.Exit(1)
+	}
+
+	if err := validateReports(g.reports); err != nil {
+		g.logger.Errorf("Error: %v", err)
+		os.Exit(1)
+	}
+
+	if err := g.notify.validate(); err != nil {
+		g.logger.Errorf("Error: %v", err)
+		os.Exit(1)
+	}
+
+	if err := g.parseFormat(); err != nil {
+		g.logger.Errorf("Error: %v", err)
+		os.Exit(1)
+	}
+
+	if err := g.transfer.validate(); err != nil {
+		g.logger.Errorf("Error: %v", err)
+		os.Exit(1)
+	}
+
+	if err := g.strategy.validate(); err != nil {
+		g.logger.Errorf("Error: %v", err)
+		os.Exit(1)
+	}
+
+	if g.tui && g.verboseGit {
+		g.logger.Errorf("Error: --verbose-git cannot be combined with --tui")
+		os.Exit(1)
+	}
+
+	if g.tui && g.interactive.enabled {
+		g.logger.Errorf("Error: --interactive cannot be combined with --tui")
+		os.Exit(1)
+	}
+
+	if g.tui && g.color.stream != "" {
+		g.logger.Errorf("Error: --stream cannot be combined with --tui")
+		os.Exit(1)
+	}
+
+	g.serveMetrics()
+
+	started := time.Now()
+
+	lock, err := g.lockRun(key)
 	if err != nil {
 		g.logger.Errorf("Error: %v", err)
+		os.Exit(1)
 	}
+	defer lock.Close()
 
-	g.wait()
+	g.loadQuarantine()
+	g.loadEstimates()
+	g.startState(key)
+
+	if g.tui {
+		g.runTUI(pull)
+	} else {
+		stopStream := g.startStream()
+		pull()
+		stopStream()
+	}
+	g.finishRun()
+	g.finishState()
+	g.saveFailed()
 
-	g.printSummary()
+	// Fabricated repositories must not influence real runs.
+	if !g.noHistory && g.sim.repos == 0 && g.historyDir != "" {
+		g.recordRun(root, started)
+	}
+
+	// NDJSON output stays parseable without the table.
+	if g.color.stream != streamNDJSON {
+		g.printSummary()
+	}
+	g.saveMetricsFile()
+	g.writeReports(root, started)
+	g.sendNotifications(root, started)
+
+	switch {
+	case g.transfer.exceeded:
+		os.Exit(1)
+	case g.cancelled():
+		os.Exit(130)
+	case g.anyFailed():
+		os.Exit(1)
+	}
 }
 
-func (g *GitPullCommand) visit(path string, info os.FileInfo, err error) error {
+// setupClient selects the git backend and prepares credential handling.
+// Simulations only replace the runner, so they require the exec backend.
+func (g *GitPullCommand) setupClient() error {
+	if g.sim.repos > 0 && g.backend != backendExec {
+		return fmt.Errorf("--simulate requires the %s backend", backendExec)
+	}
+
+	creds, err := loadCredentials(g.auth.credentialsFile)
 	if err != nil {
-		g.logger.Errorf("Error accessing path: %v", err)
-		return nil
+		return err
 	}
+	g.creds = creds
 
-	if info.IsDir() && info.Name() == ".git" {
-		repoDir := filepath.Dir(path)
-		g.wg.Add(1)
-		go g.pullRepository(repoDir)
+	if g.runner == nil {
+		env, err := g.gitEnv(creds)
+		if err != nil {
+			return err
+		}
+		// The go-git backend only needs the binary for auxiliary commands.
+		git, err := findGit()
+		if err != nil && g.backend == backendExec {
+			return err
+		}
+		g.noGit = err
+		runner := execRunner{ctx: g.ctx, git: git, env: env, settings: &g.settings}
+		if g.verboseGit {
+			runner.live = &liveOutput{out: os.Stderr, color: g.color.enabled}
+		}
+		g.runner = runner
+	}
 
-		// Skip traversing subdirectories within repositories
-		return filepath.SkipDir
+	if err := g.fetch.validate(g.backend); err != nil {
+		return err
+	}
+	if err := g.settings.validate(g.backend); err != nil {
+		return err
+	}
+	if err := g.hostKeys.validate(g.backend); err != nil {
+		return err
+	}
+	if err := validateSafeDirectory(g.safeDirectory.mode); err != nil {
+		return err
+	}
+	if err := g.requireGit(g.binaryOptions()...); err != nil {
+		return err
+	}
+
+	client, err := newGitClient(g.ctx, g.backend, g.runner, creds)
+	if err != nil {
+		return err
 	}
 
+	if g.auth.checkSSHAgent {
+		g.checkSSHAgent()
+	}
+
+	g.client = client
 	return nil
 }
 
+// pullAll discovers every repository below dir and waits for all pulls to finish.
+func (g *GitPullCommand) pullAll(dir string) {
+	g.forEachRepo(dir, g.pullRepository)
+}
+
+// forEachRepo discovers every repository below dir, runs fn for each one
+// concurrently and waits for all of them to finish.
+func (g *GitPullCommand) forEachRepo(dir string, fn func(string)) {
+	g.discover(dir, func(repoDir string) {
+		if g.addResult(repoDir, statusQueued) {
+			g.queue(repoDir, fn)
+		}
+	})
+	g.confirm()
+
+	g.wait()
+}
+
+// discover calls found for every repository below dir. When dir holds no
+// repository but lies inside one, such as a subdirectory of a working tree,
+// that repository is used instead.
+func (g *GitPullCommand) discover(dir string, found func(string)) {
+	count := 0
+	counted := func(repo string) {
+		count++
+		found(repo)
+	}
+
+	g.discoverBelow(dir, counted)
+
+	if count == 0 && g.ctx.Err() == nil {
+		if top := g.enclosingRepo(dir); top != "" {
+			g.logger.Infof("No repositories below %s; using the enclosing repository %s", dir, top)
+			found(top)
+		}
+	}
+}
+
+// discoverBelow walks dir, or uses the discovery cache when --cached is set.
+func (g *GitPullCommand) discoverBelow(dir string, found func(string)) {
+	if g.cached {
+		if repos, ok := g.cachedRepos(cacheKey(dir)); ok {
+			g.logger.Debugf("Using %d cached repositories for %s", len(repos), dir)
+			for _, repo := range repos {
+				found(repo)
+			}
+			return
+		}
+		g.logger.Infof("No cached scan of %s; walking it", dir)
+	}
+
+	g.walk(dir, found)
+}
+
+// enclosingRepo returns the top level of the working tree containing dir,
+// or "" when dir is not inside one.
+func (g *GitPullCommand) enclosingRepo(dir string) string {
+	output, err := g.runner.Run(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return ""
+	}
+	return filepath.FromSlash(strings.TrimSpace(string(output)))
+}
+
+// addResult registers a discovered repository in the summary. It reports
+// false when the repository is already known, possibly under another path
+// such as a symlinked root, so every repository is pulled once.
+func (g *GitPullCommand) addResult(dir, status string) bool {
+	r := &repoResult{Dir: dir, Key: repoKey(dir), Kind: detectRepo(dir), Status: status}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.byDir[dir] != nil {
+		return false
+	}
+	if known := g.byKey[r.Key]; known != nil {
+		g.logger.Infof("Skipping %s: it is the same repository as %s", dir, known.Dir)
+		return false
+	}
+	g.summary = append(g.summary, r)
+	g.byDir[dir] = r
+	g.byKey[r.Key] = r
+	return true
+}
+
+// repoKey canonicalizes dir: the absolute path with symlinks resolved, so
+// aliases of one repository share a key.
+func repoKey(dir string) string {
+	key := cacheKey(dir)
+	if resolved, err := filepath.EvalSymlinks(key); err == nil {
+		key = resolved
+	}
+	return key
+}
+
+// spawn runs fn for dir in the background once a worker slot is free,
+// tracked by the wait group.
+func (g *GitPullCommand) spawn(dir string, fn func(string)) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if !g.acquire() {
+			g.cancelQueued(dir)
+			return
+		}
+		defer g.release()
+		fn(dir)
+	}()
+}
+
 func (g *GitPullCommand) pullRepository(dir string) {
-	defer g.wg.Done()
+	defer g.streamResult(dir)
+	if g.sizes {
+		defer g.measureSizes(dir)
+	}
+	if g.view.wantsDetails() {
+		defer g.recordDetails(dir)
+	}
 
-	remote, status := g.getGitStatus(dir)
 	g.mu.Lock()
-	g.summary = append(g.summary, []string{dir, remote, status})
+	if g.statusOf(dir) == statusSkipped {
+		g.mu.Unlock()
+		return
+	}
+	if g.quarantined[dir] {
+		g.updateStatus(dir, statusQuarantined)
+		g.mu.Unlock()
+		g.logger.Infof("Skipping quarantined repository: %s", dir)
+		return
+	}
+	if g.resumed[cacheKey(dir)] {
+		g.updateStatus(dir, statusResumed)
+		g.mu.Unlock()
+		return
+	}
+	g.updateStatus(dir, statusPulling)
+	g.updateStarted(dir, time.Now())
+	kind := g.find(dir).Kind
+	g.mu.Unlock()
+
+	unlock := g.lockRepo(dir)
+	defer unlock()
+
+	remote, err := g.resolveRemote(dir)
+	if err != nil && g.distrusted(dir) {
+		if status, output := g.trustDirectory(dir); status != "" {
+			g.finishPull(dir, status, output)
+			return
+		}
+		remote, err = g.resolveRemote(dir)
+	}
+	if err != nil {
+		g.logger.Errorf("Error selecting remote for %s: %v", dir, err)
+		g.finishPull(dir, statusFailed, []byte(err.Error()+"\n"))
+		return
+	}
+	remote, err = g.rewriteRemote(dir, remote)
+	if err != nil {
+		g.logger.Errorf("Error rewriting remote of %s: %v", dir, err)
+		g.finishPull(dir, statusFailed, []byte(err.Error()+"\n"))
+		return
+	}
+	g.mu.Lock()
+	g.updateRemote(dir, remote)
 	g.mu.Unlock()
 
-	// Perform git pull
+	if status, output := g.checkForge(dir, remote.URL); status != "" {
+		g.finishPull(dir, status, output)
+		return
+	}
+
+	if status, output := g.checkHostKey(dir, remote.URL); status != "" {
+		g.finishPull(dir, status, output)
+		return
+	}
+
+	if !g.acquireHost(remote.URL) {
+		g.finishPull(dir, statusCancelled, nil)
+		return
+	}
+	defer g.releaseHost(remote.URL)
+
+	if g.remote.check {
+		if status, output := g.checkRemote(dir, remote); status != "" {
+			g.finishPull(dir, status, output)
+			return
+		}
+	}
+
+	// Pinned repositories are usually detached, so the branch policy does
+	// not apply to them.
+	pin := ""
+	if kind != kindBare {
+		pin = g.pinFor(dir)
+	}
+	if kind != kindBare && pin == "" {
+		if status, output := g.checkBranch(dir, remote.Name); status != "" {
+			g.finishPull(dir, status, output)
+			return
+		}
+	}
+
+	oldHead, _ := g.client.Head(dir)
+
+	if g.hooks.pre != "" {
+		output, err := g.runHook(g.hooks.pre, hookEnv{repo: dir, status: statusPulling, oldSHA: oldHead})
+		if err != nil {
+			g.logger.Errorf("Pre-hook failed in %s: %v", dir, err)
+			g.finishPull(dir, statusHookFailed, output)
+			return
+		}
+	}
+
+	var status string
+	var output []byte
+	switch {
+	case kind == kindBare:
+		status, output = g.fetchBare(dir, remote.Name)
+	case pin != "":
+		status, output = g.checkoutPin(dir, remote.Name, pin, oldHead)
+	default:
+		status, output = g.pull(dir, remote.Name, oldHead)
+		if g.popStash && (status == statusUpdated || status == statusUpToDate) {
+			output = append(output, g.popLatestStash(dir)...)
+		}
+	}
+	g.finishPull(dir, status, output)
+
+	if g.hooks.post != "" {
+		newHead, _ := g.client.Head(dir)
+		hookOutput, err := g.runHook(g.hooks.post, hookEnv{repo: dir, status: status, oldSHA: oldHead, newSHA: newHead})
+		if err != nil {
+			g.logger.Errorf("Post-hook failed in %s: %v", dir, err)
+		}
+		g.mu.Lock()
+		g.updateOutput(dir, string(output)+string(hookOutput))
+		g.mu.Unlock()
+	}
+}
+
+// fetchBare updates a bare repository, which has no working tree to merge
+// into.
+func (g *GitPullCommand) fetchBare(dir, remote string) (string, []byte) {
+	g.logger.Infof("Performing git fetch for bare repository: %s", dir)
+	output, err := g.client.Fetch(dir, remote, g.fetch)
+	g.recordReceived(dir, output)
+	if err != nil {
+		g.logger.Errorf("Error executing git fetch: %v", err)
+		if isAuthError(output, err) {
+			return statusAuthRequired, output
+		}
+		return statusFailed, output
+	}
+	return statusFetched, output
+}
+
+// pull fetches remote and integrates its branch into dir, whose HEAD was at
+// oldHead, and returns the resulting status and git output.
+func (g *GitPullCommand) pull(dir, remote, oldHead string) (string, []byte) {
+	// Perform git pull as an explicit fetch followed by integration, so
+	// a failed merge can be told apart from a failed download.
 	g.logger.Infof("Performing git pull for repository: %s", dir)
-	cmd := exec.Command("git", "-C", dir, "pull")
-	err := cmd.Run()
+
+	// A conflict kept by an earlier run must be resolved by hand first.
+	if conflicted, _ := g.client.Conflicted(dir); conflicted {
+		g.logger.Errorf("Repository %s has unresolved conflicts", dir)
+		return statusConflict, []byte("unresolved conflicts from an earlier merge or rebase\n")
+	}
+
+	output, err := g.client.Fetch(dir, remote, g.fetch)
+	g.recordReceived(dir, output)
 	if err != nil {
+		g.logger.Errorf("Error executing git fetch: %v", err)
+		if isAuthError(output, err) {
+			return statusAuthRequired, output
+		}
+		return statusFailed, output
+	}
+
+	conflict, err := g.client.WouldConflict(dir, remote)
+	switch {
+	case errors.Is(err, errNoUpstream):
 		g.logger.Errorf("Error executing git pull: %v", err)
+		return statusFailed, append(output, []byte(err.Error()+"\n")...)
+	case err != nil && g.fetchOnlyOnConflict:
+		g.logger.Warnf("Leaving %s fetched: could not predict conflicts: %v", dir, err)
+		return statusFetchedNotMerged, append(output, []byte("could not predict conflicts: "+err.Error()+"\n")...)
+	case err != nil:
+		g.logger.Debugf("Could not predict conflicts for %s: %v", dir, err)
+	case conflict && g.fetchOnlyOnConflict:
+		g.logger.Infof("Leaving %s fetched: integrating would conflict", dir)
+		return statusFetchedNotMerged, output
+	}
+
+	merged, err := g.client.Integrate(dir, remote)
+	output = append(output, merged...)
+	if err != nil {
+		if conflicted, _ := g.client.Conflicted(dir); conflicted {
+			return g.handleConflict(dir, remote, output)
+		}
+		g.logger.Errorf("Error integrating fetched changes: %v", err)
+		return statusFetchedNotMerged, output
+	}
+
+	// Compare HEAD rather than parsing git's output, which differs between
+	// merges, rebases and backends.
+	status := statusUpToDate
+	if newHead, err := g.client.Head(dir); err == nil && newHead != oldHead {
+		status = statusUpdated
+		if oldHead != "" {
+			g.countChanges(dir, oldHead, newHead)
+			g.recordLog(dir, oldHead, newHead)
+		}
+	}
+
+	if g.prune.remote {
+		pruned := g.pruneRepository(dir, remote)
 		g.mu.Lock()
-		g.updateStatus(dir, "Failed")
+		g.updatePruned(dir, pruned)
 		g.mu.Unlock()
-	} else {
-		g.mu.Lock()
-		g.updateStatus(dir, "Success")
+	}
+
+	status, lfsOutput := g.pullLFS(dir, remote, status)
+	return status, append(output, lfsOutput...)
+}
+
+// countChanges records how many commits and files a pull brought in.
+func (g *GitPullCommand) countChanges(dir, oldHead, newHead string) {
+	commits, err := g.client.CommitsBetween(dir, oldHead, newHead)
+	if err != nil {
+		g.logger.Debugf("Could not count new commits in %s: %v", dir, err)
+	}
+	files, err := g.client.ChangedFiles(dir, oldHead, newHead)
+	if err != nil {
+		g.logger.Debugf("Could not count changed files in %s: %v", dir, err)
+	}
+
+	g.mu.Lock()
+	g.updateCommits(dir, commits)
+	g.updateFiles(dir, files)
+	g.mu.Unlock()
+}
+
+// finishPull records the final status, git output and duration of a pull.
+// Pulls that did not complete because the run was interrupted count as
+// cancelled rather than failed.
+func (g *GitPullCommand) finishPull(dir, status string, output []byte) {
+	if g.cancelled() && !isSuccess(status) {
+		status = statusCancelled
+	}
+	switch {
+	case status != statusFailed && status != statusUnreachable:
+	case hostKeyPattern.Match(output):
+		status = statusHostKey
+	case ownershipPattern.Match(output):
+		status = statusOwnership
+	}
+
+	g.mu.Lock()
+	g.updateStatus(dir, status)
+	g.updateOutput(dir, string(output))
+	if r := g.find(dir); r != nil {
+		r.Duration = time.Since(r.Started)
+	}
+	g.mu.Unlock()
+
+	g.checkpoint(dir, status)
+}
+
+// retry queues a failed or skipped repository for another pull.
+func (g *GitPullCommand) retry(dir string) {
+	g.mu.Lock()
+	status := g.statusOf(dir)
+	if status != statusFailed && status != statusSkipped && status != statusQuarantined {
 		g.mu.Unlock()
+		return
+	}
+	// An explicit retry overrides the quarantine.
+	delete(g.quarantined, dir)
+	g.updateStatus(dir, statusQueued)
+	g.updateOutput(dir, "")
+	g.updateCommits(dir, 0)
+	g.mu.Unlock()
+
+	g.spawn(dir, g.pullRepository)
+}
+
+// skip marks a queued or failed repository as skipped.
+func (g *GitPullCommand) skip(dir string) {
+	g.mu.Lock()
+	status := g.statusOf(dir)
+	if status == statusQueued || status == statusFailed {
+		g.updateStatus(dir, statusSkipped)
+	}
+	g.mu.Unlock()
+}
+
+func (g *GitPullCommand) find(dir string) *repoResult {
+	return g.byDir[dir]
+}
+
+func (g *GitPullCommand) statusOf(dir string) string {
+	if r := g.find(dir); r != nil {
+		return r.Status
 	}
+	return ""
 }
 
 func (g *GitPullCommand) updateStatus(dir, status string) {
-	for i, row := range g.summary {
-		if row[0] == dir {
-			g.summary[i][2] = status
-			break
-		}
+	if r := g.find(dir); r != nil {
+		r.Status = status
 	}
 }
 
-func (g *GitPullCommand) getGitStatus(dir string) (string, string) {
-	cmd := exec.Command("git", "-C", dir, "remote", "-v")
-	output, err := cmd.Output()
-	if err != nil {
-		g.logger.Errorf("Error executing git remote: %v", err)
-		return "", "Unknown"
+func (g *GitPullCommand) updateGroup(dir, group string) {
+	if r := g.find(dir); r != nil {
+		r.Group = group
+	}
+}
+
+func (g *GitPullCommand) updateRemote(dir string, remote gitRemote) {
+	if r := g.find(dir); r != nil {
+		r.Remote = remote.URL
+		r.RemoteName = remote.Name
 	}
+}
 
-	lines := strings.Split(string(output), "\n")
-	if len(lines) < 1 {
-		return "", "Unknown"
+func (g *GitPullCommand) updateCommits(dir string, commits int) {
+	if r := g.find(dir); r != nil {
+		r.Commits = commits
 	}
+}
 
-	remoteLine := strings.TrimSpace(lines[0])
-	remoteParts := strings.Fields(remoteLine)
-	if len(remoteParts) != 3 {
-		return "", "Unknown"
+func (g *GitPullCommand) updateFiles(dir string, files int) {
+	if r := g.find(dir); r != nil {
+		r.Files = files
 	}
+}
 
-	remote := remoteParts[1]
-	return remote, "Pending"
+func (g *GitPullCommand) updatePruned(dir string, pruned int) {
+	if r := g.find(dir); r != nil {
+		r.Pruned = pruned
+	}
 }
 
-func (g *GitPullCommand) wait() {
-	g.wg.Wait()
+func (g *GitPullCommand) updateStarted(dir string, started time.Time) {
+	if r := g.find(dir); r != nil {
+		r.Started = started
+	}
 }
 
-func (g *GitPullCommand) printSummary() {
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Directory", "Remote", "Status"})
-	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
-	table.SetAutoWrapText(false)
+func (g *GitPullCommand) updateReceived(dir string, received int64, objects int) {
+	if r := g.find(dir); r != nil {
+		r.Received = received
+		r.Objects = objects
+	}
+}
 
-	for _, row := range g.summary {
-		table.Append(row)
+func (g *GitPullCommand) updateOutput(dir, output string) {
+	if r := g.find(dir); r != nil {
+		r.Output = output
 	}
+}
 
-	table.Render()
+func (g *GitPullCommand) wait() {
+	g.wg.Wait()
 }
 
 func main() {
+	if os.Getenv(envAskpassHelper) == "1" {
+		os.Exit(runAskpass(os.Args[1:]))
+	}
+
 	cmd := NewGitPullCommand()
 	if err := cmd.rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// GitClient performs the repository operations a run needs. It is
+// implemented by shelling out to git and by a pure-Go go-git backend.
+type GitClient interface {
+	Remotes(dir string) ([]gitRemote, error)
+	Status(dir string) (repoStatus, error)
+	Fetch(dir, remote string, opts fetchOptions) ([]byte, error)
+	WouldConflict(dir, remote string) (bool, error)
+	Integrate(dir, remote string) ([]byte, error)
+	Conflicted(dir string) (bool, error)
+	AbortIntegrate(dir, remote string) ([]byte, error)
+	Head(dir string) (string, error)
+	CommitsBetween(dir, from, to string) (int, error)
+	ChangedFiles(dir, from, to string) (int, error)
+	Log(dir, from, to string, max int) ([]string, error)
+	Ahead(dir string) (int, error)
+	Push(dir string, forceWithLease bool) ([]byte, error)
+}
+
+// errNoUpstream is returned by Ahead when the current branch has no upstream.
+var errNoUpstream = errors.New("no upstream configured for the current branch")
+
+// gitRemote is a configured remote and its fetch URL.
+type gitRemote struct {
+	Name string
+	URL  string
+}
+
+// repoStatus describes the checked-out branch and working tree state.
+type repoStatus struct {
+	Branch string
+	Dirty  bool
+}
+
+const (
+	backendExec  = "exec"
+	backendGoGit = "gogit"
+)
+
+func newGitClient(ctx context.Context, backend string, runner gitRunner, creds *credentialStore) (GitClient, error) {
+	switch backend {
+	case backendExec:
+		return execClient{runner: runner}, nil
+	case backendGoGit:
+		return goGitClient{ctx: ctx, creds: creds}, nil
+	}
+	return nil, fmt.Errorf("unknown backend %q (options: %s, %s)", backend, backendExec, backendGoGit)
+}
+
+// execClient implements GitClient on top of the git binary.
+type execClient struct {
+	runner gitRunner
+}
+
+func (c execClient) Remotes(dir string) ([]gitRemote, error) {
+	output, err := c.runner.Run(dir, "remote", "-v")
+	if err != nil {
+		return nil, err
+	}
+	return parseRemotes(string(output)), nil
+}
+
+// parseRemotes extracts the fetch URLs from `git remote -v` output.
+func parseRemotes(output string) []gitRemote {
+	var remotes []gitRemote
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.Fields(line)
+		if len(parts) != 3 || parts[2] != "(fetch)" {
+			continue
+		}
+		remotes = append(remotes, gitRemote{Name: parts[0], URL: parts[1]})
+	}
+	return remotes
+}
+
+func (c execClient) Status(dir string) (repoStatus, error) {
+	output, err := c.runner.Run(dir, "status", "--porcelain", "--branch")
+	if err != nil {
+		return repoStatus{}, err
+	}
+	return parseStatus(string(output)), nil
+}
+
+// parseStatus reads the branch header and change lines of
+// `git status --porcelain --branch`.
+func parseStatus(output string) repoStatus {
+	var st repoStatus
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "## "):
+			header := strings.TrimPrefix(line, "## ")
+			header = strings.TrimPrefix(header, "No commits yet on ")
+			if strings.HasPrefix(header, "HEAD ") {
+				st.Branch = "HEAD"
+				continue
+			}
+			st.Branch = strings.Fields(strings.SplitN(header, "...", 2)[0])[0]
+		case strings.TrimSpace(line) != "":
+			st.Dirty = true
+		}
+	}
+	return st
+}
+
+// Fetch asks git for progress output even though it is not writing to a
+// terminal, so the amount of data received can be reported.
+func (c execClient) Fetch(dir, remote string, opts fetchOptions) ([]byte, error) {
+	args := append([]string{"fetch", "--progress"}, opts.args()...)
+	output, err := c.runner.Run(dir, append(args, remote)...)
+	return collapseProgress(output), err
+}
+
+// collapseProgress keeps only the final state of progress lines that git
+// redraws with carriage returns.
+func collapseProgress(output []byte) []byte {
+	lines := bytes.Split(output, []byte("\n"))
+	for i, line := range lines {
+		if j := bytes.LastIndexByte(bytes.TrimRight(line, "\r"), '\r'); j >= 0 {
+			lines[i] = line[j+1:]
+		}
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// mergeRef returns the remote-tracking branch to integrate from remote: the
+// upstream when the current branch tracks remote, otherwise the branch of
+// the same upstream name on remote.
+func (c execClient) mergeRef(dir, remote string) (string, error) {
+	output, err := c.runner.Run(dir, "rev-parse", "--abbrev-ref", "@{upstream}")
+	if err != nil {
+		return "", errNoUpstream
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(output)), remote+"/") {
+		return "@{upstream}", nil
+	}
+
+	branch, err := c.runner.Run(dir, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return "", errNoUpstream
+	}
+	merge, err := c.runner.Run(dir, "config", "--get", "branch."+strings.TrimSpace(string(branch))+".merge")
+	if err != nil {
+		return "", errNoUpstream
+	}
+
+	ref := "refs/remotes/" + remote + "/" + strings.TrimPrefix(strings.TrimSpace(string(merge)), "refs/heads/")
+	if _, err := c.runner.Run(dir, "rev-parse", "--verify", "--quiet", ref); err != nil {
+		return "", fmt.Errorf("%w: %s not found", errNoUpstream, strings.TrimPrefix(ref, "refs/remotes/"))
+	}
+	return ref, nil
+}
+
+// WouldConflict reports whether integrating the fetched branch of remote
+// would stop on conflicts. Fast-forwards never conflict; diverged branches
+// are merged in memory with `git merge-tree`.
+func (c execClient) WouldConflict(dir, remote string) (bool, error) {
+	ref, err := c.mergeRef(dir, remote)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = c.runner.Run(dir, "merge-base", "--is-ancestor", "HEAD", ref)
+	if err == nil {
+		return false, nil
+	}
+	if exitCode(err) != 1 {
+		return false, err
+	}
+
+	// The tree it prints is not needed; --quiet would say so but needs
+	// git 2.40.
+	_, err = c.runner.Run(dir, "merge-tree", "--write-tree", "HEAD", ref)
+	if err == nil {
+		return false, nil
+	}
+	if exitCode(err) == 1 {
+		return true, nil
+	}
+	return false, err
+}
+
+// Integrate merges or rebases onto the fetched branch of remote, honouring
+// the same rebase settings as `git pull`.
+func (c execClient) Integrate(dir, remote string) ([]byte, error) {
+	ref, err := c.mergeRef(dir, remote)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.rebase(dir) {
+		return c.runner.Run(dir, "rebase", ref)
+	}
+	return c.runner.Run(dir, "merge", ref)
+}
+
+// Conflicted reports whether the index has unmerged paths, i.e. a merge or
+// rebase stopped on conflicts.
+func (c execClient) Conflicted(dir string) (bool, error) {
+	output, err := c.runner.Run(dir, "ls-files", "--unmerged")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(output)) != "", nil
+}
+
+// AbortIntegrate abandons a merge or rebase started by Integrate and
+// restores the branch to where it was.
+func (c execClient) AbortIntegrate(dir, remote string) ([]byte, error) {
+	if c.rebase(dir) {
+		return c.runner.Run(dir, "rebase", "--abort")
+	}
+	return c.runner.Run(dir, "merge", "--abort")
+}
+
+func (c execClient) rebase(dir string) bool {
+	keys := []string{"pull.rebase"}
+	if output, err := c.runner.Run(dir, "symbolic-ref", "--short", "HEAD"); err == nil {
+		keys = append([]string{"branch." + strings.TrimSpace(string(output)) + ".rebase"}, keys...)
+	}
+
+	for _, key := range keys {
+		output, err := c.runner.Run(dir, "config", "--get", key)
+		if err != nil {
+			continue
+		}
+		// Besides true, rebase accepts merges and interactive.
+		return strings.TrimSpace(string(output)) != "false"
+	}
+	return false
+}
+
+// exitCode returns the exit status of a failed git invocation, or -1 when
+// the error did not come from the process exiting.
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+func (c execClient) Head(dir string) (string, error) {
+	output, err := c.runner.Run(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// CommitsBetween counts the commits reachable from to but not from.
+func (c execClient) CommitsBetween(dir, from, to string) (int, error) {
+	output, err := c.runner.Run(dir, "rev-list", "--count", from+".."+to)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(output)))
+}
+
+// ChangedFiles counts the paths that differ between two commits.
+func (c execClient) ChangedFiles(dir, from, to string) (int, error) {
+	output, err := c.runner.Run(dir, "diff", "--name-only", "-z", from, to)
+	if err != nil {
+		return 0, err
+	}
+	return bytes.Count(output, []byte{0}), nil
+}
+
+// Log returns up to max one-line summaries of the commits reachable from to
+// but not from, newest first.
+func (c execClient) Log(dir, from, to string, max int) ([]string, error) {
+	output, err := c.runner.Run(dir, "log", "--format=%h %s", "-n", strconv.Itoa(max), from+".."+to)
+	if err != nil {
+		return nil, err
+	}
+	return strings.FieldsFunc(string(output), func(r rune) bool { return r == '\n' }), nil
+}
+
+func (c execClient) Ahead(dir string) (int, error) {
+	if _, err := c.runner.Run(dir, "rev-parse", "--abbrev-ref", "@{upstream}"); err != nil {
+		return 0, errNoUpstream
+	}
+
+	output, err := c.runner.Run(dir, "rev-list", "--count", "@{upstream}..HEAD")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(output)))
+}
+
+func (c execClient) Push(dir string, forceWithLease bool) ([]byte, error) {
+	args := []string{"push"}
+	if forceWithLease {
+		args = append(args, "--force-with-lease")
+	}
+	return c.runner.Run(dir, args...)
+}
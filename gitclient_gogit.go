@@ -0,0 +1,427 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// goGitClient implements GitClient with go-git, for systems without a git
+// binary. Pulls are limited to fast-forwards, as in go-git itself.
+type goGitClient struct {
+	ctx   context.Context
+	creds *credentialStore
+}
+
+func (goGitClient) open(dir string) (*git.Repository, error) {
+	return git.PlainOpen(dir)
+}
+
+// authFor returns basic auth for HTTPS remotes with a stored token.
+func (c goGitClient) authFor(repo *git.Repository, remoteName string) transport.AuthMethod {
+	if c.creds == nil {
+		return nil
+	}
+
+	remote, err := repo.Remote(remoteName)
+	if err != nil || len(remote.Config().URLs) == 0 {
+		return nil
+	}
+
+	cred, ok := c.creds.forURL(remote.Config().URLs[0])
+	if !ok {
+		return nil
+	}
+	return &githttp.BasicAuth{Username: cred.username, Password: cred.token}
+}
+
+func (c goGitClient) Remotes(dir string) ([]gitRemote, error) {
+	repo, err := c.open(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := repo.Remotes()
+	if err != nil {
+		return nil, err
+	}
+
+	remotes := make([]gitRemote, 0, len(list))
+	for _, r := range list {
+		cfg := r.Config()
+		if len(cfg.URLs) == 0 {
+			continue
+		}
+		remotes = append(remotes, gitRemote{Name: cfg.Name, URL: cfg.URLs[0]})
+	}
+
+	// Match the alphabetical order of `git remote -v`.
+	sort.Slice(remotes, func(i, j int) bool { return remotes[i].Name < remotes[j].Name })
+	return remotes, nil
+}
+
+func (c goGitClient) Status(dir string) (repoStatus, error) {
+	repo, err := c.open(dir)
+	if err != nil {
+		return repoStatus{}, err
+	}
+
+	var st repoStatus
+	head, err := repo.Head()
+	switch {
+	case err != nil:
+		return st, err
+	case head.Name().IsBranch():
+		st.Branch = head.Name().Short()
+	default:
+		st.Branch = "HEAD"
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return st, err
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return st, err
+	}
+	st.Dirty = !status.IsClean()
+
+	return st, nil
+}
+
+func (c goGitClient) Fetch(dir, remote string, opts fetchOptions) ([]byte, error) {
+	repo, err := c.open(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := git.TagFollowing
+	if opts.tags {
+		tags = git.AllTags
+	}
+
+	var progress bytes.Buffer
+	err = repo.FetchContext(c.ctx, &git.FetchOptions{
+		RemoteName: remote,
+		Depth:      opts.depth,
+		Tags:       tags,
+		Progress:   &progress,
+		Auth:       c.authFor(repo, remote),
+	})
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		err = nil
+	}
+	return collapseProgress(progress.Bytes()), err
+}
+
+// upstream resolves the checked-out branch and the remote branch it tracks.
+func (c goGitClient) upstream(repo *git.Repository) (*plumbing.Reference, *config.Branch, error) {
+	head, err := repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil, errNoUpstream
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !head.Name().IsBranch() {
+		return nil, nil, errNoUpstream
+	}
+
+	cfg, err := repo.Config()
+	if err != nil {
+		return nil, nil, err
+	}
+	branch, ok := cfg.Branches[head.Name().Short()]
+	if !ok || branch.Remote == "" || branch.Merge == "" {
+		return nil, nil, errNoUpstream
+	}
+
+	return head, branch, nil
+}
+
+func (c goGitClient) Ahead(dir string) (int, error) {
+	repo, err := c.open(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	head, branch, err := c.upstream(repo)
+	if err != nil {
+		return 0, err
+	}
+
+	upstream, err := repo.Reference(plumbing.NewRemoteReferenceName(branch.Remote, branch.Merge.Short()), true)
+	if err != nil {
+		return 0, errNoUpstream
+	}
+
+	// Count commits reachable from HEAD but not from the upstream branch,
+	// like `git rev-list --count @{upstream}..HEAD`.
+	return c.countBetween(repo, upstream.Hash(), head.Hash())
+}
+
+// countBetween counts the commits reachable from to but not from.
+func (c goGitClient) countBetween(repo *git.Repository, from, to plumbing.Hash) (int, error) {
+	known := map[plumbing.Hash]bool{}
+	commits, err := repo.Log(&git.LogOptions{From: from})
+	if err != nil {
+		return 0, err
+	}
+	err = commits.ForEach(func(commit *object.Commit) error {
+		known[commit.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	commits, err = repo.Log(&git.LogOptions{From: to})
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	err = commits.ForEach(func(commit *object.Commit) error {
+		if !known[commit.Hash] {
+			count++
+		}
+		return nil
+	})
+
+	return count, err
+}
+
+func (c goGitClient) Head(dir string) (string, error) {
+	repo, err := c.open(dir)
+	if err != nil {
+		return "", err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+	return head.Hash().String(), nil
+}
+
+func (c goGitClient) CommitsBetween(dir, from, to string) (int, error) {
+	repo, err := c.open(dir)
+	if err != nil {
+		return 0, err
+	}
+	return c.countBetween(repo, plumbing.NewHash(from), plumbing.NewHash(to))
+}
+
+func (c goGitClient) Log(dir, from, to string, max int) ([]string, error) {
+	repo, err := c.open(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[plumbing.Hash]bool{}
+	commits, err := repo.Log(&git.LogOptions{From: plumbing.NewHash(from)})
+	if err != nil {
+		return nil, err
+	}
+	err = commits.ForEach(func(commit *object.Commit) error {
+		known[commit.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err = repo.Log(&git.LogOptions{From: plumbing.NewHash(to)})
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	err = commits.ForEach(func(commit *object.Commit) error {
+		if len(lines) == max {
+			return storer.ErrStop
+		}
+		if !known[commit.Hash] {
+			subject, _, _ := strings.Cut(commit.Message, "\n")
+			lines = append(lines, commit.Hash.String()[:7]+" "+subject)
+		}
+		return nil
+	})
+	return lines, err
+}
+
+func (c goGitClient) ChangedFiles(dir, from, to string) (int, error) {
+	repo, err := c.open(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var trees [2]*object.Tree
+	for i, hash := range []string{from, to} {
+		commit, err := repo.CommitObject(plumbing.NewHash(hash))
+		if err != nil {
+			return 0, err
+		}
+		if trees[i], err = commit.Tree(); err != nil {
+			return 0, err
+		}
+	}
+
+	changes, err := object.DiffTree(trees[0], trees[1])
+	return len(changes), err
+}
+
+func (c goGitClient) Push(dir string, forceWithLease bool) ([]byte, error) {
+	repo, err := c.open(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	head, branch, err := c.upstream(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var progress bytes.Buffer
+	opts := &git.PushOptions{
+		RemoteName: branch.Remote,
+		RefSpecs:   []config.RefSpec{config.RefSpec(head.Name().String() + ":" + branch.Merge.String())},
+		Progress:   &progress,
+		Auth:       c.authFor(repo, branch.Remote),
+	}
+	if forceWithLease {
+		opts.ForceWithLease = &git.ForceWithLease{}
+	}
+
+	err = repo.PushContext(c.ctx, opts)
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		progress.WriteString("Everything up-to-date\n")
+		err = nil
+	}
+	return progress.Bytes(), err
+}
+
+// upstreamCommits resolves the commits at HEAD and at the branch of
+// remoteName with the same name as its upstream.
+func (c goGitClient) upstreamCommits(repo *git.Repository, remoteName string) (*object.Commit, *object.Commit, error) {
+	head, branch, err := c.upstream(repo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	upstream, err := repo.Reference(plumbing.NewRemoteReferenceName(remoteName, branch.Merge.Short()), true)
+	if err != nil {
+		return nil, nil, errNoUpstream
+	}
+
+	local, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, nil, err
+	}
+	remote, err := repo.CommitObject(upstream.Hash())
+	if err != nil {
+		return nil, nil, err
+	}
+	return local, remote, nil
+}
+
+// WouldConflict treats any non-fast-forward as a conflict, since go-git
+// cannot perform three-way merges. A branch only ahead of its upstream has
+// nothing to integrate.
+func (c goGitClient) WouldConflict(dir, remoteName string) (bool, error) {
+	repo, err := c.open(dir)
+	if err != nil {
+		return false, err
+	}
+
+	local, remote, err := c.upstreamCommits(repo, remoteName)
+	if err != nil {
+		return false, err
+	}
+
+	if ahead, err := remote.IsAncestor(local); err != nil || ahead {
+		return false, err
+	}
+	ff, err := local.IsAncestor(remote)
+	return !ff, err
+}
+
+// Integrate fast-forwards the current branch to the fetched branch of
+// remoteName.
+func (c goGitClient) Integrate(dir, remoteName string) ([]byte, error) {
+	repo, err := c.open(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	local, remote, err := c.upstreamCommits(repo, remoteName)
+	if err != nil {
+		return nil, err
+	}
+	// Equal commits, or a branch only ahead of its upstream.
+	ahead, err := remote.IsAncestor(local)
+	if err != nil {
+		return nil, err
+	}
+	if ahead {
+		return []byte("Already up to date.\n"), nil
+	}
+
+	ff, err := local.IsAncestor(remote)
+	if err != nil {
+		return nil, err
+	}
+	if !ff {
+		return nil, git.ErrNonFastForwardUpdate
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	err = wt.Reset(&git.ResetOptions{Commit: remote.Hash, Mode: git.MergeReset})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("Fast-forward %s..%s\n", local.Hash.String()[:7], remote.Hash.String()[:7])), nil
+}
+
+// Conflicted reports unmerged paths. Integrate only fast-forwards, so they
+// can only come from a merge started outside gitpull.
+func (c goGitClient) Conflicted(dir string) (bool, error) {
+	repo, err := c.open(dir)
+	if err != nil {
+		return false, err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return false, err
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return false, err
+	}
+
+	for _, s := range status {
+		if s.Staging == git.UpdatedButUnmerged || s.Worktree == git.UpdatedButUnmerged {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AbortIntegrate has nothing to undo: Integrate never leaves a merge in
+// progress.
+func (c goGitClient) AbortIntegrate(dir, remote string) ([]byte, error) {
+	return nil, nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// repoGroup is a named set of repositories from the config file, run with
+// gitpull group <name>, e.g.
+//
+//	groups:
+//	  work:
+//	    dirs: [~/work, ~/clients]
+//	    exclude: ["*-archive"]
+//	    options:
+//	      prune: true
+//	      jobs: 4
+//
+// Include and exclude are glob patterns matched like the shell's: against
+// the path below the group directory or the repository's base name.
+// Options are flag names and values applied to the run unless the flag is
+// given on the command line.
+type repoGroup struct {
+	Dirs    []string               `yaml:"dirs"`
+	Include []string               `yaml:"include"`
+	Exclude []string               `yaml:"exclude"`
+	Options map[string]interface{} `yaml:"options"`
+}
+
+func (g *GitPullCommand) newGroupCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "group [name...]",
+		Short: "Pull the repositories of groups defined in the config file, or list the groups",
+		Args:  cobra.ArbitraryArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return g.applyGroups(cmd, args)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				g.listGroups(cmd)
+				return
+			}
+			if g.sim.repos > 0 {
+				g.logger.Errorf("Error: --simulate cannot be combined with groups")
+				os.Exit(1)
+			}
+			root := "group:" + strings.Join(args, ",")
+			g.execute(root, root, func() { g.pullGroups(args) })
+		},
+	}
+
+	// A group run takes the same options as a run of a single directory.
+	cmd.Flags().AddFlagSet(g.rootCmd.LocalNonPersistentFlags())
+	return cmd
+}
+
+// applyGroups checks the named groups and applies their options. Options
+// override the top-level config file but not the command line; with several
+// groups, later ones win.
+func (g *GitPullCommand) applyGroups(cmd *cobra.Command, names []string) error {
+	for _, name := range names {
+		group, ok := g.groups[name]
+		if !ok {
+			return fmt.Errorf("unknown group %q (defined: %s)", name, strings.Join(g.groupNames(), ", "))
+		}
+		if len(group.Dirs) == 0 {
+			return fmt.Errorf("group %s has no dirs", name)
+		}
+		for _, pattern := range append(append([]string{}, group.Include...), group.Exclude...) {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("group %s: invalid pattern %q: %v", name, pattern, err)
+			}
+		}
+
+		for key, value := range group.Options {
+			flag := cmd.Flags().Lookup(key)
+			if flag == nil {
+				return fmt.Errorf("group %s: unknown option %q", name, key)
+			}
+			if flag.Changed && !g.fromConfig[key] {
+				continue
+			}
+			if err := setFlag(flag, value); err != nil {
+				return fmt.Errorf("group %s: %s: %v", name, key, err)
+			}
+			g.fromConfig[key] = true
+		}
+	}
+	return g.configure()
+}
+
+func (g *GitPullCommand) groupNames() []string {
+	names := make([]string, 0, len(g.groups))
+	for name := range g.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (g *GitPullCommand) listGroups(cmd *cobra.Command) {
+	if len(g.groups) == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "No groups defined in %s\n", g.configPath)
+		return
+	}
+	for _, name := range g.groupNames() {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", name, strings.Join(g.groups[name].Dirs, ", "))
+	}
+}
+
+// pullGroups pulls every repository of the named groups and waits for all
+// pulls to finish. A repository in several groups is pulled once, under the
+// first group listing it.
+func (g *GitPullCommand) pullGroups(names []string) {
+	for _, name := range names {
+		group := g.groups[name]
+		for _, dir := range group.Dirs {
+			dir = expandHome(dir)
+			g.discover(dir, func(repoDir string) {
+				if !group.admits(dir, repoDir) {
+					return
+				}
+				if !g.addResult(repoDir, statusQueued) {
+					return
+				}
+				g.mu.Lock()
+				g.updateGroup(repoDir, name)
+				g.mu.Unlock()
+				g.queue(repoDir, g.pullRepository)
+			})
+		}
+	}
+	g.confirm()
+
+	g.wait()
+}
+
+// admits reports whether a repository found below root belongs to the group.
+func (group repoGroup) admits(root, dir string) bool {
+	if len(group.Include) > 0 {
+		included := false
+		for _, pattern := range group.Include {
+			if matchesPath(root, dir, pattern) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, pattern := range group.Exclude {
+		if matchesPath(root, dir, pattern) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// historyKeep is the number of run records kept on disk.
+const historyKeep = 100
+
+// historyTimeFormat names run files so they sort chronologically.
+const historyTimeFormat = "20060102T150405.000000000Z"
+
+// runRecord is the persisted outcome of one run.
+type runRecord struct {
+	Root     string         `json:"root"`
+	Started  time.Time      `json:"started"`
+	Finished time.Time      `json:"finished"`
+	Results  []historyEntry `json:"results"`
+}
+
+// historyEntry is the persisted outcome for one repository.
+type historyEntry struct {
+	Dir      string  `json:"dir"`
+	Remote   string  `json:"remote"`
+	Status   string  `json:"status"`
+	Duration float64 `json:"duration_seconds,omitempty"`
+}
+
+// historyStore keeps one JSON file per run in dir.
+type historyStore struct {
+	dir string
+}
+
+// defaultHistoryDir follows the XDG base directory layout.
+func defaultHistoryDir() string {
+	base := os.Getenv("XDG_DATA_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		base = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(base, "gitpuller", "history")
+}
+
+// files returns the run files, oldest first.
+func (h historyStore) files() ([]string, error) {
+	entries, err := os.ReadDir(h.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, filepath.Join(h.dir, e.Name()))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// load returns up to n of the most recent runs, newest first.
+func (h historyStore) load(n int) ([]runRecord, error) {
+	names, err := h.files()
+	if err != nil {
+		return nil, err
+	}
+	if len(names) > n {
+		names = names[len(names)-n:]
+	}
+
+	records := make([]runRecord, 0, len(names))
+	for i := len(names) - 1; i >= 0; i-- {
+		data, err := os.ReadFile(names[i])
+		if err != nil {
+			return nil, err
+		}
+		var rec runRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// save writes rec and removes runs beyond historyKeep.
+func (h historyStore) save(rec runRecord) error {
+	if err := os.MkdirAll(h.dir, 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := filepath.Join(h.dir, rec.Started.UTC().Format(historyTimeFormat)+".json")
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		return err
+	}
+
+	names, err := h.files()
+	if err != nil {
+		return err
+	}
+	for len(names) > historyKeep {
+		os.Remove(names[0])
+		names = names[1:]
+	}
+	return nil
+}
+
+// recordRun persists the results of the current run.
+func (g *GitPullCommand) recordRun(root string, started time.Time) {
+	rec := runRecord{Root: root, Started: started, Finished: time.Now()}
+	for _, r := range g.summary {
+		rec.Results = append(rec.Results, historyEntry{Dir: r.Dir, Remote: r.Remote, Status: r.Status, Duration: r.Duration.Seconds()})
+	}
+
+	if err := g.historyStore().save(rec); err != nil {
+		g.logger.Errorf("Error saving run history: %v", err)
+	}
+}
+
+func (g *GitPullCommand) historyStore() historyStore {
+	return historyStore{dir: g.historyDir}
+}
+
+// historyChange is one difference between two runs of the same root.
+type historyChange struct {
+	dir    string
+	change string
+	before string
+	after  string
+}
+
+func (g *GitPullCommand) newHistoryCommand() *cobra.Command {
+	limit := 10
+
+	cmd := &cobra.Command{
+		Use:   "history [dir]",
+		Short: "List recent runs, optionally only those of one directory",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			records, err := g.runsOf(args, limit)
+			if err != nil {
+				return err
+			}
+			if len(records) == 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), g.msg(msgNoHistory)+"\n", g.historyDir)
+				return nil
+			}
+
+			rows := make([][]string, 0, len(records))
+			for _, rec := range records {
+				results := rec.results()
+				rows = append(rows, []string{
+					formatDate(rec.Started),
+					rec.Root,
+					strconv.Itoa(len(results)),
+					formatDuration(rec.Finished.Sub(rec.Started)),
+					g.summaryLine(results),
+				})
+			}
+			g.renderTable([]string{g.msg(msgStarted), g.msg(msgDirectory), g.msg(msgRepositories), g.msg(msgDuration), g.msg(msgOutcomes)}, rows)
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&limit, "limit", limit, "Number of runs to list")
+
+	return cmd
+}
+
+func (g *GitPullCommand) newDiffLastCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff-last [dir]",
+		Short: "Show newly failing, recovered, new and vanished repositories since the previous run",
+		Long: "Compare the latest run with the run before it of the same directory, or of\n" +
+			"the directory of the latest run when none is given.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			records, err := g.runsOf(args, historyKeep)
+			if err != nil {
+				return err
+			}
+			if len(records) > 0 && len(args) == 0 {
+				records, err = g.runsOf([]string{records[0].Root}, historyKeep)
+				if err != nil {
+					return err
+				}
+			}
+			if len(records) < 2 {
+				fmt.Fprintln(cmd.OutOrStdout(), g.msg(msgNoPreviousRun))
+				return nil
+			}
+
+			latest, previous := records[0], records[1]
+			fmt.Fprintf(cmd.OutOrStdout(), g.msg(msgComparingRuns)+"\n", latest.Root, formatDate(previous.Started), formatDate(latest.Started))
+			changes := diffRuns(previous, latest)
+			if len(changes) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), g.msg(msgNoChanges))
+				return nil
+			}
+
+			rows := make([][]string, 0, len(changes))
+			for _, c := range changes {
+				before, after := c.before, c.after
+				if before != "" {
+					before = g.msg(before)
+				}
+				if after != "" {
+					after = g.msg(after)
+				}
+				rows = append(rows, []string{c.dir, g.msg(c.change), before, after})
+			}
+			g.renderTable([]string{g.msg(msgDirectory), g.msg(msgChange), g.msg(msgBefore), g.msg(msgAfter)}, rows)
+			return nil
+		},
+	}
+}
+
+// runsOf returns up to limit recent runs, newest first, restricted to the
+// directory in args when one is given. Roots are compared as given and as
+// absolute paths, so a run of "." matches the current directory.
+func (g *GitPullCommand) runsOf(args []string, limit int) ([]runRecord, error) {
+	records, err := g.historyStore().load(historyKeep)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []runRecord
+	for _, rec := range records {
+		if len(selected) == limit {
+			break
+		}
+		if len(args) == 0 || rec.Root == args[0] || (!strings.HasPrefix(rec.Root, "group:") && cacheKey(rec.Root) == cacheKey(args[0])) {
+			selected = append(selected, rec)
+		}
+	}
+	return selected, nil
+}
+
+// results turns the entries of a run back into results for summaryLine.
+func (rec runRecord) results() []*repoResult {
+	results := make([]*repoResult, len(rec.Results))
+	for i, e := range rec.Results {
+		results[i] = &repoResult{Dir: e.Dir, Remote: e.Remote, Status: e.Status}
+	}
+	return results
+}
+
+// diffRuns lists the repositories that started or stopped failing, and those
+// that appeared or disappeared, between two runs. Repositories whose status
+// merely moved between successful outcomes, such as Updated and Up-to-date,
+// are left out.
+func diffRuns(previous, latest runRecord) []historyChange {
+	failed := viewOptions{only: onlyFailed}
+	before := map[string]*repoResult{}
+	for _, r := range previous.results() {
+		before[r.Dir] = r
+	}
+
+	var changes []historyChange
+	seen := map[string]bool{}
+	for _, r := range latest.results() {
+		seen[r.Dir] = true
+		old, ok := before[r.Dir]
+		switch {
+		case !ok:
+			changes = append(changes, historyChange{dir: r.Dir, change: msgChangeNew, after: r.Status})
+		case failed.matches(r) && !failed.matches(old):
+			changes = append(changes, historyChange{dir: r.Dir, change: msgChangeFailing, before: old.Status, after: r.Status})
+		case !failed.matches(r) && failed.matches(old):
+			changes = append(changes, historyChange{dir: r.Dir, change: msgChangeRecovered, before: old.Status, after: r.Status})
+		}
+	}
+	for _, r := range previous.results() {
+		if !seen[r.Dir] {
+			changes = append(changes, historyChange{dir: r.Dir, change: msgChangeGone, before: r.Status})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// hookOptions are shell commands run in each repository around its pull.
+type hookOptions struct {
+	pre  string
+	post string
+}
+
+func (g *GitPullCommand) addHookFlags() {
+	flags := g.rootCmd.PersistentFlags()
+	flags.StringVar(&g.hooks.pre, "pre-hook", "", "Shell command run in each repository before pulling; failure skips the pull")
+	flags.StringVar(&g.hooks.post, "post-hook", "", "Shell command run in each repository after pulling")
+}
+
+// hookEnv describes the repository to a hook.
+type hookEnv struct {
+	repo   string
+	status string
+	oldSHA string
+	newSHA string
+}
+
+// runHook executes command with the system shell inside the repository.
+func (g *GitPullCommand) runHook(command string, env hookEnv) ([]byte, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(g.ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(g.ctx, "sh", "-c", command)
+	}
+
+	// Hooks often run git themselves, so they see the repository's settings.
+	repoEnv, _ := g.settings.forDir(env.repo)
+
+	cmd.Dir = env.repo
+	cmd.Env = append(append(os.Environ(), repoEnv...),
+		"GITPULL_REPO="+env.repo,
+		"GITPULL_STATUS="+env.status,
+		"GITPULL_OLD_SHA="+env.oldSHA,
+		"GITPULL_NEW_SHA="+env.newSHA,
+	)
+	return cmd.CombinedOutput()
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// hostKeyOptions make sure pulls from SSH hosts missing from known_hosts
+// fail up front instead of stopping at ssh's host key prompt. The known
+// map caches the check per host and is guarded by g.mu.
+type hostKeyOptions struct {
+	acceptNew bool
+	known     map[string]bool
+}
+
+func (g *GitPullCommand) addHostKeyFlags() {
+	g.rootCmd.PersistentFlags().BoolVar(&g.hostKeys.acceptNew, "accept-new-hostkeys", false, "Let ssh add the keys of hosts missing from known_hosts (StrictHostKeyChecking=accept-new via GIT_SSH_COMMAND); changed keys are still rejected")
+}
+
+func (o hostKeyOptions) validate(backend string) error {
+	if o.acceptNew && backend != backendExec {
+		return fmt.Errorf("--accept-new-hostkeys requires the %s backend", backendExec)
+	}
+	return nil
+}
+
+// sshCommand returns GIT_SSH_COMMAND for --accept-new-hostkeys, extending
+// the command the user set if any.
+func (o hostKeyOptions) sshCommand() string {
+	command := os.Getenv("GIT_SSH_COMMAND")
+	if command == "" {
+		command = "ssh"
+	}
+	return command + " -o StrictHostKeyChecking=accept-new"
+}
+
+// hostKeyPattern matches ssh's messages for unknown and changed host keys.
+var hostKeyPattern = regexp.MustCompile(`Host key verification failed|REMOTE HOST IDENTIFICATION HAS CHANGED|No \w* ?host key is known`)
+
+// sshHost returns the host and port of an SSH remote URL, in both the
+// ssh://host:port/path and the scp-like host:path forms.
+func sshHost(rawURL string) (host, port string, ok bool) {
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		switch u.Scheme {
+		case "ssh", "git+ssh", "ssh+git":
+			return u.Hostname(), u.Port(), true
+		}
+		return "", "", false
+	}
+	if strings.Contains(rawURL, "://") {
+		return "", "", false
+	}
+	host, _ = splitRemoteURL(rawURL)
+	return host, "", host != ""
+}
+
+// checkHostKey returns the status to finish the pull with when the SSH
+// host of remoteURL is not in known_hosts, or "" when the pull may go ahead.
+func (g *GitPullCommand) checkHostKey(dir, remoteURL string) (string, []byte) {
+	if g.hostKeys.acceptNew {
+		return "", nil
+	}
+	host, port, ok := sshHost(remoteURL)
+	if !ok {
+		return "", nil
+	}
+
+	target := host
+	if port != "" {
+		target = host + ":" + port
+	}
+	g.mu.Lock()
+	known, checked := g.hostKeys.known[target]
+	g.mu.Unlock()
+	if !checked {
+		var err error
+		known, err = knownHost(g.ctx, host, port)
+		if err != nil {
+			g.logger.Debugf("Could not check the host key of %s: %v", target, err)
+			known = true
+		}
+		g.mu.Lock()
+		if g.hostKeys.known == nil {
+			g.hostKeys.known = map[string]bool{}
+		}
+		g.hostKeys.known[target] = known
+		g.mu.Unlock()
+	}
+	if known {
+		return "", nil
+	}
+
+	g.logger.Errorf("Host key of %s for %s is not in known_hosts", target, dir)
+	return statusHostKey, []byte(fmt.Sprintf("the host key of %s is not in known_hosts: add it with ssh-keyscan or pass --accept-new-hostkeys\n", target))
+}
+
+// knownHost asks ssh how it would connect to host, following Host aliases
+// in ~/.ssh/config, and looks the host up in the known_hosts files ssh
+// would read. Hosts ssh does not check strictly count as known.
+func knownHost(ctx context.Context, host, port string) (bool, error) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		return false, err
+	}
+
+	args := []string{"-G"}
+	if port != "" {
+		args = append(args, "-p", port)
+	}
+	output, err := exec.CommandContext(ctx, "ssh", append(args, host)...).Output()
+	if err != nil {
+		return false, err
+	}
+
+	config := map[string][]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), " "); ok {
+			config[key] = append(config[key], strings.Fields(value)...)
+		}
+	}
+	first := func(key string) string {
+		if values := config[key]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	switch first("stricthostkeychecking") {
+	case "false", "no", "off", "accept-new":
+		return true, nil
+	}
+
+	name := first("hostname")
+	if alias := first("hostkeyalias"); alias != "" && alias != "none" {
+		name = alias
+	}
+	if p := first("port"); p != "" && p != "22" {
+		name = "[" + name + "]:" + p
+	}
+
+	for _, file := range append(config["userknownhostsfile"], config["globalknownhostsfile"]...) {
+		file = expandHome(file)
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		if err := exec.CommandContext(ctx, "ssh-keygen", "-F", name, "-f", file).Run(); err == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
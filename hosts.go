@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// hostOptions throttle pulls that talk to the same remote host, independently
+// of --jobs, so large organisations on one forge do not trip abuse limits.
+type hostOptions struct {
+	concurrency int
+	delay       time.Duration
+
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+	next  map[string]time.Time
+}
+
+func (g *GitPullCommand) addHostFlags() {
+	flags := g.rootCmd.PersistentFlags()
+	flags.IntVar(&g.hosts.concurrency, "per-host-concurrency", 0, "Maximum number of repositories pulled at once from the same remote host (0 means no limit)")
+	flags.DurationVar(&g.hosts.delay, "per-host-delay", 0, "Minimum time between the starts of pulls from the same remote host, e.g. 500ms")
+}
+
+func (o *hostOptions) validate() error {
+	if o.concurrency < 0 {
+		return fmt.Errorf("--per-host-concurrency must not be negative")
+	}
+	if o.delay < 0 {
+		return fmt.Errorf("--per-host-delay must not be negative")
+	}
+	return nil
+}
+
+// acquireHost waits until a pull from the host of remoteURL may start and
+// reports false when the run is cancelled first. Local remotes are not
+// throttled.
+func (g *GitPullCommand) acquireHost(remoteURL string) bool {
+	host, _ := splitRemoteURL(remoteURL)
+	if host == "" || (g.hosts.concurrency == 0 && g.hosts.delay == 0) {
+		return true
+	}
+
+	if slots := g.hosts.slotsFor(host); slots != nil {
+		select {
+		case slots <- struct{}{}:
+		case <-g.ctx.Done():
+			return false
+		}
+	}
+
+	if wait := g.hosts.reserveStart(host); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-g.ctx.Done():
+			g.releaseHost(remoteURL)
+			return false
+		}
+	}
+	return true
+}
+
+func (g *GitPullCommand) releaseHost(remoteURL string) {
+	host, _ := splitRemoteURL(remoteURL)
+	if host == "" {
+		return
+	}
+	if slots := g.hosts.slotsFor(host); slots != nil {
+		<-slots
+	}
+}
+
+func (o *hostOptions) slotsFor(host string) chan struct{} {
+	if o.concurrency == 0 {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.slots == nil {
+		o.slots = map[string]chan struct{}{}
+	}
+	slots, ok := o.slots[host]
+	if !ok {
+		slots = make(chan struct{}, o.concurrency)
+		o.slots[host] = slots
+	}
+	return slots
+}
+
+// reserveStart books the next start time for host and returns how long the
+// caller has to wait for it.
+func (o *hostOptions) reserveStart(host string) time.Duration {
+	if o.delay == 0 {
+		return 0
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.next == nil {
+		o.next = map[string]time.Time{}
+	}
+	now := time.Now()
+	start := o.next[host]
+	if start.Before(now) {
+		start = now
+	}
+	o.next[host] = start.Add(o.delay)
+	return start.Sub(now)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// ignoreFileName marks subtrees that discovery leaves alone, using
+// .gitignore syntax. Patterns apply to the directory holding the file and
+// everything below it, and files further down can re-include paths with !.
+const ignoreFileName = ".gitpullerignore"
+
+// readIgnoreFile parses the ignore file in dir, whose path relative to the
+// walk root is domain.
+func readIgnoreFile(dir string, domain []string) ([]gitignore.Pattern, error) {
+	file, err := os.Open(longPath(filepath.Join(dir, ignoreFileName)))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var patterns []gitignore.Pattern
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "#") && strings.TrimSpace(line) != "" {
+			patterns = append(patterns, gitignore.ParsePattern(line, domain))
+		}
+	}
+	return patterns, scanner.Err()
+}
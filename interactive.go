@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// interactiveOptions hold back the repositories a run discovers until they
+// are confirmed, so a shared directory is not pulled wholesale.
+type interactiveOptions struct {
+	enabled bool
+	pending []queuedRepo
+}
+
+type queuedRepo struct {
+	dir string
+	fn  func(string)
+}
+
+func (g *GitPullCommand) addInteractiveFlags() {
+	g.rootCmd.Flags().BoolVarP(&g.interactive.enabled, "interactive", "i", false, "List the discovered repositories with their branch and working tree state and choose which to pull")
+}
+
+// queue runs fn for dir like spawn, or holds it for confirm with
+// --interactive or a --schedule-strategy other than fifo.
+func (g *GitPullCommand) queue(dir string, fn func(string)) {
+	if !g.interactive.enabled && !g.strategy.ordered() {
+		g.spawn(dir, fn)
+		return
+	}
+	g.mu.Lock()
+	g.interactive.pending = append(g.interactive.pending, queuedRepo{dir: dir, fn: fn})
+	g.mu.Unlock()
+}
+
+// confirm asks which of the held repositories to pull and starts them.
+// Deselected repositories are skipped; aborting the prompt cancels the run.
+// Without --interactive the held repositories start right away.
+func (g *GitPullCommand) confirm() {
+	g.mu.Lock()
+	pending := g.interactive.pending
+	g.interactive.pending = nil
+	g.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+	if !g.interactive.enabled {
+		g.startQueued(pending)
+		return
+	}
+
+	states := make([][]string, len(pending))
+	for i, p := range pending {
+		states[i] = g.describeRepo(p.dir)
+	}
+	selected := make([]bool, len(pending))
+	for i := range selected {
+		selected[i] = true
+	}
+
+	in := bufio.NewScanner(g.rootCmd.InOrStdin())
+	out := g.rootCmd.OutOrStdout()
+	for {
+		rows := make([][]string, len(pending))
+		count := 0
+		for i, p := range pending {
+			mark := "[ ]"
+			if selected[i] {
+				mark = "[x]"
+				count++
+			}
+			rows[i] = append([]string{strconv.Itoa(i + 1), mark, p.dir}, states[i]...)
+		}
+		g.renderTable([]string{"#", "", g.msg(msgDirectory), g.msg(msgBranch), g.msg(msgWorkingTree)}, rows)
+		fmt.Fprintf(out, "Toggle repositories by number or range (e.g. 2 4-6), 'all' or 'none'; press Enter to pull %d of %d, 'q' to abort: ", count, len(pending))
+
+		if !in.Scan() {
+			fmt.Fprintln(out)
+			g.abortInteractive(pending)
+			return
+		}
+		answer := strings.TrimSpace(in.Text())
+		switch answer {
+		case "":
+			for i, p := range pending {
+				if !selected[i] {
+					g.skip(p.dir)
+				}
+			}
+			g.startQueued(pending)
+			return
+		case "q", "quit":
+			g.abortInteractive(pending)
+			return
+		case "a", "all", "n", "none":
+			for i := range selected {
+				selected[i] = answer == "a" || answer == "all"
+			}
+			continue
+		}
+
+		toggled, err := parseSelection(answer, len(pending))
+		if err != nil {
+			fmt.Fprintf(out, "Invalid selection: %v\n", err)
+			continue
+		}
+		for _, i := range toggled {
+			selected[i] = !selected[i]
+		}
+	}
+}
+
+// abortInteractive cancels the run, so the held repositories end up
+// cancelled rather than pulled.
+func (g *GitPullCommand) abortInteractive(pending []queuedRepo) {
+	g.logger.Infof("Aborted: nothing was pulled")
+	g.cancel()
+	for _, p := range pending {
+		g.spawn(p.dir, p.fn)
+	}
+}
+
+// describeRepo returns the branch and working tree state shown for dir.
+func (g *GitPullCommand) describeRepo(dir string) []string {
+	g.mu.Lock()
+	kind := g.find(dir).Kind
+	g.mu.Unlock()
+	if kind == kindBare {
+		return []string{"", g.msg(msgKindBare)}
+	}
+
+	st, err := g.client.Status(dir)
+	if err != nil {
+		g.logger.Debugf("Could not read the status of %s: %v", dir, err)
+		return []string{"", "?"}
+	}
+	if st.Dirty {
+		return []string{st.Branch, g.msg(msgDirty)}
+	}
+	return []string{st.Branch, g.msg(msgClean)}
+}
+
+// parseSelection reads numbers and ranges such as "1 3-5,7" into zero-based
+// indexes below n.
+func parseSelection(answer string, n int) ([]int, error) {
+	var indexes []int
+	for _, field := range strings.FieldsFunc(answer, func(r rune) bool { return r == ' ' || r == ',' }) {
+		first, last, isRange := strings.Cut(field, "-")
+		from, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number or range", field)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(last); err != nil {
+				return nil, fmt.Errorf("%q is not a number or range", field)
+			}
+		}
+		if from < 1 || to > n || from > to {
+			return nil, fmt.Errorf("%q is not between 1 and %d", field, n)
+		}
+		for i := from; i <= to; i++ {
+			indexes = append(indexes, i-1)
+		}
+	}
+	return indexes, nil
+}
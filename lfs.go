@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// lfsAttribute marks paths stored in Git LFS in .gitattributes.
+const lfsAttribute = "filter=lfs"
+
+func (g *GitPullCommand) addLFSFlags() {
+	g.rootCmd.PersistentFlags().BoolVar(&g.lfs, "lfs", false, "Run git lfs pull after pulling repositories that use Git LFS")
+}
+
+// usesLFS reports whether any .gitattributes file tracked in dir routes
+// paths through the LFS filter.
+func (g *GitPullCommand) usesLFS(dir string) bool {
+	files := []string{".gitattributes"}
+	if output, err := g.runner.Run(dir, "ls-files", "--", ":(glob)**/.gitattributes"); err == nil {
+		files = append(files, strings.Fields(string(output))...)
+	}
+
+	for _, name := range files {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err == nil && bytes.Contains(data, []byte(lfsAttribute)) {
+			return true
+		}
+	}
+	return false
+}
+
+// pullLFS downloads the LFS objects for the checked-out commit and replaces
+// pointer files in the working tree, keeping the pull's status unless the
+// download fails. It needs git-lfs installed.
+func (g *GitPullCommand) pullLFS(dir, remote, status string) (string, []byte) {
+	if !g.usesLFS(dir) {
+		return status, nil
+	}
+	if !g.lfs {
+		g.logger.Infof("%s uses Git LFS; pass --lfs to download its files", dir)
+		return status, nil
+	}
+
+	g.logger.Infof("Performing git lfs pull for repository: %s", dir)
+	output, err := g.runner.Run(dir, "lfs", "pull", remote)
+	if err != nil {
+		g.logger.Errorf("Error executing git lfs pull in %s: %v", dir, err)
+		return statusLFSFailed, output
+	}
+	return status, output
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// liveOutput writes the output of git commands as it arrives with
+// --verbose-git, one line at a time prefixed with the repository, like
+// docker-compose logs. All pulls share it, so lines never interleave.
+type liveOutput struct {
+	mu     sync.Mutex
+	out    io.Writer
+	color  bool
+	colors map[string]string
+}
+
+// livePalette colors the prefixes of consecutive repositories apart.
+var livePalette = []string{"\x1b[36m", "\x1b[35m", "\x1b[34m", "\x1b[32m", "\x1b[33m", "\x1b[96m", "\x1b[95m", "\x1b[94m"}
+
+func (g *GitPullCommand) addLiveFlags() {
+	g.rootCmd.PersistentFlags().BoolVar(&g.verboseGit, "verbose-git", false, "Stream the output of every git command live to stderr, prefixed with the repository")
+}
+
+// command prints the git command about to run in dir.
+func (l *liveOutput) command(dir string, args []string) {
+	l.line(dir, "$ git "+strings.Join(args, " "))
+}
+
+func (l *liveOutput) line(dir, text string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	prefix := dir + " |"
+	if l.color {
+		if l.colors == nil {
+			l.colors = map[string]string{}
+		}
+		color, ok := l.colors[dir]
+		if !ok {
+			color = livePalette[len(l.colors)%len(livePalette)]
+			l.colors[dir] = color
+		}
+		prefix = color + prefix + colorReset
+	}
+	fmt.Fprintln(l.out, prefix, text)
+}
+
+// writer returns a writer for the output of one command in dir. Progress
+// updates ending in a carriage return count as lines.
+func (l *liveOutput) writer(dir string) *lineWriter {
+	return &lineWriter{live: l, dir: dir}
+}
+
+type lineWriter struct {
+	live    *liveOutput
+	dir     string
+	pending []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexAny(w.pending, "\r\n")
+		if i < 0 {
+			return len(p), nil
+		}
+		if line := strings.TrimSpace(string(w.pending[:i])); line != "" {
+			w.live.line(w.dir, line)
+		}
+		w.pending = w.pending[i+1:]
+	}
+}
+
+// flush prints a last line that did not end in a newline.
+func (w *lineWriter) flush() {
+	if line := strings.TrimSpace(string(w.pending)); line != "" {
+		w.live.line(w.dir, line)
+	}
+	w.pending = nil
+}
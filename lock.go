@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lockPollInterval is how often --wait retries a held instance lock.
+const lockPollInterval = 500 * time.Millisecond
+
+// errLocked reports an instance lock held by another process.
+var errLocked = errors.New("locked")
+
+// errLockUnsupported reports a platform without file locks.
+var errLockUnsupported = errors.New("file locking is not supported on this platform")
+
+// lockOptions control what happens when another run of the same root is
+// still going.
+type lockOptions struct {
+	dir   string
+	wait  bool
+	force bool
+}
+
+// defaultLockDir keeps instance locks next to the run state.
+func defaultLockDir() string {
+	state := defaultStateFile()
+	if state == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(state), "locks")
+}
+
+func (g *GitPullCommand) addLockFlags() {
+	flags := g.rootCmd.Flags()
+	flags.StringVar(&g.lock.dir, "lock-dir", defaultLockDir(), "Directory holding the locks that keep runs of the same directory from overlapping (empty disables locking)")
+	flags.BoolVar(&g.lock.wait, "wait", false, "Wait for a running invocation on the same directory to finish instead of aborting")
+	flags.BoolVar(&g.lock.force, "force", false, "Take over the lock of a running invocation on the same directory")
+}
+
+// lockPath names the lock of the run identified by key.
+func (o lockOptions) lockPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(o.dir, hex.EncodeToString(sum[:8])+".lock")
+}
+
+// lockRun takes the instance lock for key and records this process's pid in
+// it. The lock is released when the returned file is closed or the process
+// exits, so a crashed run never leaves a stale lock behind.
+func (g *GitPullCommand) lockRun(key string) (*os.File, error) {
+	if g.lock.dir == "" || g.sim.repos > 0 {
+		return nil, nil
+	}
+	if err := os.MkdirAll(g.lock.dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := g.lock.lockPath(key)
+
+	waiting := false
+	for {
+		file, err := openLock(path)
+		if err != nil {
+			return nil, err
+		}
+
+		err = tryLock(file)
+		if errors.Is(err, errLockUnsupported) {
+			g.logger.Warnf("Not locking %s: %v, so overlapping runs are not prevented", key, err)
+			err = nil
+		}
+		if err == nil {
+			file.Truncate(0)
+			file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+			return file, nil
+		}
+		file.Close()
+		if !errors.Is(err, errLocked) {
+			return nil, err
+		}
+
+		holder := lockHolder(path)
+		switch {
+		case g.lock.force:
+			// The holder keeps its lock on the old file; removing it lets
+			// this run lock a new one.
+			g.logger.Warnf("Taking over the lock of %s from process %s", key, holder)
+			if err := os.Remove(path); err != nil {
+				return nil, err
+			}
+			continue
+		case !g.lock.wait:
+			return nil, fmt.Errorf("another run (pid %s) is pulling %s; use --wait to queue behind it or --force to take over", holder, key)
+		}
+
+		if !waiting {
+			g.logger.Warnf("Waiting for the run of process %s on %s to finish", holder, key)
+			waiting = true
+		}
+		select {
+		case <-time.After(lockPollInterval):
+		case <-g.ctx.Done():
+			return nil, fmt.Errorf("interrupted while waiting for the lock of %s", key)
+		}
+	}
+}
+
+// lockHolder returns the pid recorded in a lock file.
+func lockHolder(path string) string {
+	data, err := os.ReadFile(path)
+	if pid := strings.TrimSpace(string(data)); err == nil && pid != "" {
+		return pid
+	}
+	return "unknown"
+}
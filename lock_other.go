@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package main
+
+import "os"
+
+func openLock(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+}
+
+// tryLock cannot lock files on this platform, so overlapping runs are not
+// prevented.
+func tryLock(file *os.File) error {
+	return errLockUnsupported
+}
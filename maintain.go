@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// maintainOptions are the flags of the maintain subcommand.
+type maintainOptions struct {
+	gc   bool
+	fsck bool
+}
+
+func (g *GitPullCommand) newMaintainCommand() *cobra.Command {
+	var opts maintainOptions
+
+	cmd := &cobra.Command{
+		Use:   "maintain <dir>",
+		Short: "Run git maintenance on every repository and report reclaimed space",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			stopSignals := g.handleSignals()
+			defer stopSignals()
+
+			if err := g.setupClient(); err != nil {
+				g.logger.Errorf("Error: %v", err)
+				os.Exit(1)
+			}
+			if err := g.requireGit("gitpull maintain"); err != nil {
+				g.logger.Errorf("Error: %v", err)
+				os.Exit(1)
+			}
+
+			g.forEachRepo(args[0], func(dir string) { g.maintainRepository(dir, opts) })
+			g.printMaintainSummary()
+
+			failed := viewOptions{only: onlyFailed}
+			for _, r := range g.summary {
+				if failed.matches(r) {
+					os.Exit(1)
+				}
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.gc, "gc", false, "Run git gc --auto instead of git maintenance run")
+	cmd.Flags().BoolVar(&opts.fsck, "fsck", false, "Also check the object database with git fsck")
+
+	return cmd
+}
+
+// maintainRepository compacts the object database of dir and, with --fsck,
+// verifies it.
+func (g *GitPullCommand) maintainRepository(dir string, opts maintainOptions) {
+	g.mu.Lock()
+	g.updateStatus(dir, statusMaintaining)
+	g.updateStarted(dir, time.Now())
+	g.mu.Unlock()
+
+	// Worktrees share one object database, and gc refuses to run twice.
+	unlock := g.lockRepo(dir)
+	defer unlock()
+
+	objects := filepath.Join(commonDir(dir), "objects")
+	before := dirSize(objects)
+
+	args := []string{"maintenance", "run"}
+	if opts.gc {
+		args = []string{"gc", "--auto"}
+	}
+	g.logger.Infof("Performing git %s for repository: %s", args[0], dir)
+	output, err := g.runner.Run(dir, args...)
+	status := statusMaintained
+	if err != nil {
+		g.logger.Errorf("Error executing git %s in %s: %v", args[0], dir, err)
+		status = statusFailed
+	}
+
+	// A failed gc often points at corruption, so fsck still runs.
+	if opts.fsck {
+		var fsckOutput []byte
+		fsckOutput, err = g.runner.Run(dir, "fsck", "--no-progress")
+		output = append(output, fsckOutput...)
+		if err != nil {
+			g.logger.Errorf("Corruption found in %s: %v", dir, err)
+			status = statusCorrupt
+		}
+	}
+
+	after := dirSize(objects)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.updateOutput(dir, string(output))
+	if r := g.find(dir); r != nil {
+		r.Size = after
+		r.Reclaimed = before - after
+		r.Duration = time.Since(r.Started)
+	}
+	g.updateStatus(dir, status)
+}
+
+// dirSize returns the total size of the files below dir.
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+func (g *GitPullCommand) printMaintainSummary() {
+	rows := make([][]string, 0, len(g.summary))
+	for _, r := range g.summary {
+		reclaimed := ""
+		if r.Reclaimed > 0 {
+			reclaimed = formatBytes(r.Reclaimed)
+		}
+		rows = append(rows, []string{r.Dir, g.msg(r.Status), formatBytes(r.Size), reclaimed, formatDuration(r.Duration)})
+	}
+
+	g.renderTable([]string{g.msg(msgDirectory), g.msg(msgStatus), g.msg(msgSize), g.msg(msgReclaimed), g.msg(msgDuration)}, rows)
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Message keys for user-facing labels. Repository statuses are keys too: the
+// status constants double as their own catalog entries.
+const (
+	msgDirectory = "summary.directory"
+	msgRemote    = "summary.remote"
+	msgStatus    = "summary.status"
+	msgCommits   = "summary.commits"
+	msgFiles     = "summary.files"
+	msgOutput    = "summary.output"
+	msgPruned    = "summary.pruned"
+	msgDuration  = "summary.duration"
+	msgReceived  = "summary.received"
+	msgKind      = "summary.kind"
+	msgGroup     = "summary.group"
+	msgSize      = "summary.size"
+	msgReclaimed = "summary.reclaimed"
+	msgGitSize   = "summary.git_size"
+	msgTreeSize  = "summary.tree_size"
+	msgTotal     = "summary.total"
+	msgLargest   = "summary.largest"
+	msgTagBefore = "summary.tag_before"
+	msgTagAfter  = "summary.tag_after"
+	msgBranch    = "summary.branch"
+	msgAhead     = "summary.ahead"
+	msgBehind    = "summary.behind"
+	msgAuthor    = "summary.author"
+	msgDate      = "summary.date"
+	msgStashes   = "summary.stashes"
+
+	msgStash      = "stash.stash"
+	msgAge        = "stash.age"
+	msgMessage    = "stash.message"
+	msgNoStashes  = "stash.none"
+	msgStashTotal = "stash.total"
+
+	msgStreamCommits = "stream.commits"
+
+	msgIssues = "repair.issues"
+
+	msgObjects       = "summary.objects"
+	msgTransferTotal = "summary.transfer_total"
+
+	msgWorkingTree = "interactive.working_tree"
+	msgClean       = "interactive.clean"
+	msgDirty       = "interactive.dirty"
+
+	msgArchived      = "summary.archived"
+	msgDefaultBranch = "summary.default_branch"
+	msgOpenPRs       = "summary.open_prs"
+	msgYes           = "summary.yes"
+	msgNo            = "summary.no"
+
+	msgSpeedup = "summary.speedup"
+
+	msgNoFailed = "retry.none"
+	msgFixed    = "repair.fixed"
+
+	msgStarted         = "history.started"
+	msgOutcomes        = "history.outcomes"
+	msgNoHistory       = "history.none"
+	msgNoPreviousRun   = "history.no_previous"
+	msgComparingRuns   = "history.comparing"
+	msgNoChanges       = "history.no_changes"
+	msgChange          = "history.change"
+	msgBefore          = "history.before"
+	msgAfter           = "history.after"
+	msgChangeNew       = "history.change_new"
+	msgChangeGone      = "history.change_gone"
+	msgChangeFailing   = "history.change_failing"
+	msgChangeRecovered = "history.change_recovered"
+
+	msgActivitySince     = "activity.since"
+	msgActivityAuthors   = "activity.authors"
+	msgActivityUntouched = "activity.untouched"
+	msgAuthors           = "activity.author_count"
+	msgAuthorName        = "activity.author"
+	msgKindRepo          = "kind.repo"
+	msgKindWorktree      = "kind.worktree"
+	msgKindBare          = "kind.bare"
+
+	msgOutcomeUpdated   = "outcome.updated"
+	msgOutcomeFailed    = "outcome.failed"
+	msgOutcomeUpToDate  = "outcome.up_to_date"
+	msgOutcomeFetched   = "outcome.fetched"
+	msgOutcomeSkipped   = "outcome.skipped"
+	msgOutcomeCancelled = "outcome.cancelled"
+
+	msgRepositories = "tui.repositories"
+	msgLog          = "tui.log"
+	msgRunning      = "tui.running"
+	msgDone         = "tui.done"
+	msgRepos        = "tui.repos"
+	msgNoOutput     = "tui.no_output"
+	msgEscToClose   = "tui.esc_to_close"
+	msgKeyOutput    = "tui.key.output"
+	msgKeyRetry     = "tui.key.retry"
+	msgKeySkip      = "tui.key.skip"
+	msgKeyQuit      = "tui.key.quit"
+)
+
+const defaultLang = "en"
+
+// catalogs maps a language code to its translated messages. English is the
+// reference catalog; other languages fall back to it for missing keys.
+var catalogs = map[string]map[string]string{
+	"en": {
+		statusPending:          "Pending",
+		statusQueued:           "Queued",
+		statusPulling:          "Pulling",
+		statusUpdated:          "Updated",
+		statusUpToDate:         "Up-to-date",
+		statusFailed:           "Failed",
+		statusSkipped:          "Skipped",
+		statusFetchedNotMerged: "FetchedNotMerged",
+		statusPushing:          "Pushing",
+		statusPushed:           "Pushed",
+		statusWouldPush:        "Would push",
+		statusNothingToPush:    "Nothing to push",
+		statusNoUpstream:       "No upstream",
+		msgDirectory:           "Directory",
+		msgRemote:              "Remote",
+		msgStatus:              "Status",
+		msgCommits:             "Commits",
+		msgRepositories:        "Repositories",
+		msgLog:                 "Log",
+		msgRunning:             "running",
+		msgDone:                "done",
+		msgRepos:               "repos",
+		msgNoOutput:            "(no output)",
+		msgEscToClose:          "esc to close",
+		msgKeyOutput:           "output",
+		msgKeyRetry:            "retry",
+		msgKeySkip:             "skip",
+		msgKeyQuit:             "quit",
+		msgPruned:              "Pruned",
+		statusAuthRequired:     "Auth required",
+		statusQuarantined:      "Quarantined",
+		msgDuration:            "Duration",
+		statusHookFailed:       "Hook failed",
+		statusFetched:          "Fetched",
+		msgKind:                "Type",
+		msgKindRepo:            "Repository",
+		msgKindWorktree:        "Worktree",
+		msgKindBare:            "Bare",
+		statusConflict:         "Conflict",
+		msgReceived:            "Received",
+		statusLFSFailed:        "LFS failed",
+		msgOutcomeUpdated:      "updated",
+		msgOutcomeFailed:       "failed",
+		msgOutcomeUpToDate:     "up-to-date",
+		msgOutcomeFetched:      "fetched",
+		msgOutcomeSkipped:      "skipped",
+		msgFiles:               "Files",
+		statusCancelled:        "Cancelled",
+		msgOutcomeCancelled:    "cancelled",
+		msgOutput:              "Output",
+		statusResumed:          "Already pulled",
+		msgGroup:               "Group",
+		statusMaintaining:      "Maintaining",
+		statusMaintained:       "Maintained",
+		statusCorrupt:          "Corrupt",
+		msgSize:                "Size",
+		msgReclaimed:           "Reclaimed",
+		msgGitSize:             "Git size",
+		msgTreeSize:            "Working tree",
+		msgTotal:               "Total",
+		msgLargest:             "Largest repositories",
+		statusFetching:         "Fetching",
+		statusNewRelease:       "New release",
+		statusNoNewRelease:     "No new release",
+		msgTagBefore:           "Previous tag",
+		msgTagAfter:            "Latest tag",
+		msgBranch:              "Branch",
+		msgAhead:               "Ahead",
+		msgBehind:              "Behind",
+		msgAuthor:              "Last author",
+		msgDate:                "Last commit",
+		statusCloned:           "Cloned",
+		statusUnreachable:      "Remote unreachable",
+		msgActivitySince:       "Commits since",
+		msgActivityAuthors:     "Commits per author",
+		msgActivityUntouched:   "Untouched repositories",
+		msgAuthors:             "Authors",
+		msgAuthorName:          "Author",
+		msgStashes:             "Stashes",
+		msgStash:               "Stash",
+		msgAge:                 "Age",
+		msgMessage:             "Message",
+		msgNoStashes:           "No stashes found",
+		msgStashTotal:          "%d stashes in %d repositories",
+		msgStreamCommits:       "%d commits",
+		msgStarted:             "Started",
+		msgOutcomes:            "Outcome",
+		msgNoHistory:           "No runs recorded in %s",
+		msgNoPreviousRun:       "Fewer than two runs recorded; nothing to compare",
+		msgComparingRuns:       "Changes in %s between the runs of %s and %s",
+		msgNoChanges:           "No changes",
+		msgChange:              "Change",
+		msgBefore:              "Before",
+		msgAfter:               "After",
+		msgChangeNew:           "New",
+		msgChangeGone:          "Gone",
+		msgChangeFailing:       "Newly failing",
+		msgChangeRecovered:     "Recovered",
+		statusBranchCheck:      "Protected branch check",
+		statusRepairing:        "Repairing",
+		statusRepaired:         "Repaired",
+		statusHealthy:          "Healthy",
+		statusNeedsRepair:      "Needs repair",
+		msgIssues:              "Issues",
+		msgFixed:               "fixed",
+		msgObjects:             "Objects",
+		msgTransferTotal:       "Received %s in %d objects in total",
+		msgWorkingTree:         "Working tree",
+		msgClean:               "clean",
+		msgDirty:               "changes",
+		statusPinned:           "Pinned (up to date)",
+		statusPinnedMoved:      "Pinned (moved)",
+		statusHostKey:          "Host key verification required",
+		statusArchived:         "Archived",
+		msgArchived:            "Archived",
+		msgDefaultBranch:       "Default branch",
+		msgOpenPRs:             "Open PRs",
+		msgYes:                 "yes",
+		msgNo:                  "no",
+		statusOwnership:        "Ownership mismatch",
+		msgSpeedup:             "Pulls took %s in %s: %.1fx speedup (%s)",
+		msgNoFailed:            "No failed repositories listed in %s",
+	},
+	"de": {
+		statusPending:          "Ausstehend",
+		statusQueued:           "Wartend",
+		statusPulling:          "Wird geholt",
+		statusUpdated:          "Aktualisiert",
+		statusUpToDate:         "Aktuell",
+		statusFailed:           "Fehlgeschlagen",
+		statusSkipped:          "Übersprungen",
+		statusFetchedNotMerged: "Geholt, nicht gemergt",
+		statusPushing:          "Wird gepusht",
+		statusPushed:           "Gepusht",
+		statusWouldPush:        "Würde pushen",
+		statusNothingToPush:    "Nichts zu pushen",
+		statusNoUpstream:       "Kein Upstream",
+		msgDirectory:           "Verzeichnis",
+		msgRemote:              "Remote",
+		msgStatus:              "Status",
+		msgCommits:             "Commits",
+		msgRepositories:        "Repositories",
+		msgLog:                 "Protokoll",
+		msgRunning:             "läuft",
+		msgDone:                "fertig",
+		msgRepos:               "Repos",
+		msgNoOutput:            "(keine Ausgabe)",
+		msgEscToClose:          "Esc zum Schließen",
+		msgKeyOutput:           "Ausgabe",
+		msgKeyRetry:            "wiederholen",
+		msgKeySkip:             "überspringen",
+		msgKeyQuit:             "beenden",
+		msgPruned:              "Bereinigt",
+		statusAuthRequired:     "Anmeldung erforderlich",
+		statusQuarantined:      "In Quarantäne",
+		msgDuration:            "Dauer",
+		statusHookFailed:       "Hook fehlgeschlagen",
+		statusFetched:          "Geholt",
+		msgKind:                "Typ",
+		msgKindRepo:            "Repository",
+		msgKindWorktree:        "Worktree",
+		msgKindBare:            "Bare",
+		statusConflict:         "Konflikt",
+		msgReceived:            "Empfangen",
+		statusLFSFailed:        "LFS fehlgeschlagen",
+		msgOutcomeUpdated:      "aktualisiert",
+		msgOutcomeFailed:       "fehlgeschlagen",
+		msgOutcomeUpToDate:     "aktuell",
+		msgOutcomeFetched:      "geholt",
+		msgOutcomeSkipped:      "übersprungen",
+		msgFiles:               "Dateien",
+		statusCancelled:        "Abgebrochen",
+		msgOutcomeCancelled:    "abgebrochen",
+		msgOutput:              "Ausgabe",
+		statusResumed:          "Bereits geholt",
+		msgGroup:               "Gruppe",
+		statusMaintaining:      "Wird gewartet",
+		statusMaintained:       "Gewartet",
+		statusCorrupt:          "Beschädigt",
+		msgSize:                "Größe",
+		msgReclaimed:           "Freigegeben",
+		msgGitSize:             "Git-Größe",
+		msgTreeSize:            "Arbeitsverzeichnis",
+		msgTotal:               "Gesamt",
+		msgLargest:             "Größte Repositories",
+		statusFetching:         "Wird abgerufen",
+		statusNewRelease:       "Neues Release",
+		statusNoNewRelease:     "Kein neues Release",
+		msgTagBefore:           "Vorheriges Tag",
+		msgTagAfter:            "Neuestes Tag",
+		msgBranch:              "Branch",
+		msgAhead:               "Voraus",
+		msgBehind:              "Zurück",
+		msgAuthor:              "Letzter Autor",
+		msgDate:                "Letzter Commit",
+		statusCloned:           "Geklont",
+		statusUnreachable:      "Remote nicht erreichbar",
+		msgActivitySince:       "Commits seit",
+		msgActivityAuthors:     "Commits pro Autor",
+		msgActivityUntouched:   "Unveränderte Repositories",
+		msgAuthors:             "Autoren",
+		msgAuthorName:          "Autor",
+		msgStashes:             "Stashes",
+		msgStash:               "Stash",
+		msgAge:                 "Alter",
+		msgMessage:             "Nachricht",
+		msgNoStashes:           "Keine Stashes gefunden",
+		msgStashTotal:          "%d Stashes in %d Repositories",
+		msgStreamCommits:       "%d Commits",
+		msgStarted:             "Gestartet",
+		msgOutcomes:            "Ergebnis",
+		msgNoHistory:           "Keine Läufe in %s aufgezeichnet",
+		msgNoPreviousRun:       "Weniger als zwei Läufe aufgezeichnet; nichts zu vergleichen",
+		msgComparingRuns:       "Änderungen in %s zwischen den Läufen vom %s und %s",
+		msgNoChanges:           "Keine Änderungen",
+		msgChange:              "Änderung",
+		msgBefore:              "Vorher",
+		msgAfter:               "Nachher",
+		msgChangeNew:           "Neu",
+		msgChangeGone:          "Verschwunden",
+		msgChangeFailing:       "Neu fehlgeschlagen",
+		msgChangeRecovered:     "Wieder erfolgreich",
+		statusBranchCheck:      "Branch-Schutz",
+		statusRepairing:        "Wird repariert",
+		statusRepaired:         "Repariert",
+		statusHealthy:          "Intakt",
+		statusNeedsRepair:      "Reparatur nötig",
+		msgIssues:              "Probleme",
+		msgFixed:               "behoben",
+		msgObjects:             "Objekte",
+		msgTransferTotal:       "Insgesamt %s in %d Objekten empfangen",
+		msgWorkingTree:         "Arbeitsverzeichnis",
+		msgClean:               "sauber",
+		msgDirty:               "Änderungen",
+		statusPinned:           "Fixiert (aktuell)",
+		statusPinnedMoved:      "Fixiert (verschoben)",
+		statusHostKey:          "Host-Key-Prüfung erforderlich",
+		statusArchived:         "Archiviert",
+		msgArchived:            "Archiviert",
+		msgDefaultBranch:       "Standard-Branch",
+		msgOpenPRs:             "Offene PRs",
+		msgYes:                 "ja",
+		msgNo:                  "nein",
+		statusOwnership:        "Falscher Eigentümer",
+		msgSpeedup:             "Pulls dauerten %s in %s: %.1fx schneller (%s)",
+		msgNoFailed:            "Keine fehlgeschlagenen Repositories in %s aufgeführt",
+	},
+}
+
+// resolveLang picks the catalog for --lang, falling back to the locale
+// environment and finally English.
+func resolveLang(lang string) (string, error) {
+	if lang != "" {
+		if _, ok := catalogs[lang]; !ok {
+			return "", fmt.Errorf("unsupported language %q (available: %s)", lang, strings.Join(languages(), ", "))
+		}
+		return lang, nil
+	}
+
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		code := strings.ToLower(strings.SplitN(value, "_", 2)[0])
+		if _, ok := catalogs[code]; ok {
+			return code, nil
+		}
+		break
+	}
+
+	return defaultLang, nil
+}
+
+func languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// msg translates a message key into the selected language.
+func (g *GitPullCommand) msg(key string) string {
+	if text, ok := catalogs[g.lang][key]; ok {
+		return text
+	}
+	if text, ok := catalogs[defaultLang][key]; ok {
+		return text
+	}
+	return key
+}
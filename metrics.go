@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// metricsOptions exports run results in the Prometheus text format.
+type metricsOptions struct {
+	file   string
+	listen string
+}
+
+func (g *GitPullCommand) addMetricsFlags() {
+	g.rootCmd.Flags().StringVar(&g.metrics.file, "metrics-file", "", "Write Prometheus metrics for the textfile collector to this path after the run")
+	g.rootCmd.PersistentFlags().StringVar(&g.metrics.listen, "metrics-listen", "", "Serve live Prometheus metrics on this address (e.g. :9123) while running")
+}
+
+// writeMetrics renders the current results. The caller must hold g.mu.
+func (g *GitPullCommand) writeMetrics(w io.Writer) {
+	failed := viewOptions{only: onlyFailed}
+	counts := map[string]int{}
+	failures := 0
+	for _, r := range g.summary {
+		counts[r.Status]++
+		if failed.matches(r) {
+			failures++
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP gitpull_repos_total Repositories discovered in the run.")
+	fmt.Fprintln(w, "# TYPE gitpull_repos_total gauge")
+	fmt.Fprintf(w, "gitpull_repos_total %d\n", len(g.summary))
+
+	fmt.Fprintln(w, "# HELP gitpull_repos Repositories by status.")
+	fmt.Fprintln(w, "# TYPE gitpull_repos gauge")
+	statuses := make([]string, 0, len(counts))
+	for status := range counts {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		fmt.Fprintf(w, "gitpull_repos{status=\"%s\"} %d\n", escapeLabel(status), counts[status])
+	}
+
+	fmt.Fprintln(w, "# HELP gitpull_pulls_failed Repositories that failed or need attention.")
+	fmt.Fprintln(w, "# TYPE gitpull_pulls_failed gauge")
+	fmt.Fprintf(w, "gitpull_pulls_failed %d\n", failures)
+
+	fmt.Fprintln(w, "# HELP gitpull_pull_duration_seconds Duration of the last pull of each repository.")
+	fmt.Fprintln(w, "# TYPE gitpull_pull_duration_seconds gauge")
+	for _, r := range g.summary {
+		if r.Duration == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "gitpull_pull_duration_seconds{repo=\"%s\",status=\"%s\"} %g\n",
+			escapeLabel(r.Dir), escapeLabel(r.Status), r.Duration.Seconds())
+	}
+
+	if !g.lastRun.IsZero() {
+		fmt.Fprintln(w, "# HELP gitpull_last_run_timestamp_seconds Time the last run finished.")
+		fmt.Fprintln(w, "# TYPE gitpull_last_run_timestamp_seconds gauge")
+		fmt.Fprintf(w, "gitpull_last_run_timestamp_seconds %d\n", g.lastRun.Unix())
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+// finishRun records when the run finished for the last-run metric.
+func (g *GitPullCommand) finishRun() {
+	g.mu.Lock()
+	g.lastRun = time.Now()
+	g.mu.Unlock()
+}
+
+// saveMetricsFile writes --metrics-file. The textfile collector may read it
+// at any moment, so it is replaced atomically.
+func (g *GitPullCommand) saveMetricsFile() {
+	if g.metrics.file == "" {
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(g.metrics.file), ".gitpull-metrics-")
+	if err != nil {
+		g.logger.Errorf("Error writing metrics: %v", err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	g.mu.Lock()
+	g.writeMetrics(tmp)
+	g.mu.Unlock()
+
+	if err := tmp.Close(); err != nil {
+		g.logger.Errorf("Error writing metrics: %v", err)
+		return
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		g.logger.Errorf("Error writing metrics: %v", err)
+		return
+	}
+	if err := os.Rename(tmp.Name(), g.metrics.file); err != nil {
+		g.logger.Errorf("Error writing metrics: %v", err)
+	}
+}
+
+// serveMetrics exposes /metrics on --metrics-listen for as long as the
+// process runs.
+func (g *GitPullCommand) serveMetrics() {
+	if g.metrics.listen == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		// Render first so a slow scraper does not hold up pulls.
+		var buf bytes.Buffer
+		g.mu.Lock()
+		g.writeMetrics(&buf)
+		g.mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(buf.Bytes())
+	})
+
+	go func() {
+		if err := http.ListenAndServe(g.metrics.listen, mux); err != nil {
+			g.logger.Errorf("Error serving metrics: %v", err)
+		}
+	}()
+}
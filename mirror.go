@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// mirrorManifest lists the repositories the mirror subcommand backs up, e.g.
+//
+//	repos:
+//	  - url: git@github.com:org/app.git
+//	  - url: https://gitlab.com/org/tools.git
+//	    path: tools.git
+//
+// Path is relative to the backup directory and defaults to the host and
+// path of the URL, here github.com/org/app.git.
+type mirrorManifest struct {
+	Repos []mirrorEntry `yaml:"repos"`
+}
+
+type mirrorEntry struct {
+	URL  string `yaml:"url"`
+	Path string `yaml:"path"`
+}
+
+// mirrorOptions are the flags of the mirror subcommand.
+type mirrorOptions struct {
+	manifest string
+	fsck     bool
+}
+
+func (g *GitPullCommand) newMirrorCommand() *cobra.Command {
+	var opts mirrorOptions
+
+	cmd := &cobra.Command{
+		Use:   "mirror --manifest <file> <dir>",
+		Short: "Clone or update bare mirrors of the repositories listed in a manifest",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			stopSignals := g.handleSignals()
+			defer stopSignals()
+
+			entries, err := readManifest(opts.manifest)
+			if err != nil {
+				g.logger.Errorf("Error reading manifest: %v", err)
+				os.Exit(1)
+			}
+			if err := g.setupClient(); err != nil {
+				g.logger.Errorf("Error: %v", err)
+				os.Exit(1)
+			}
+			if err := g.requireGit("gitpull mirror"); err != nil {
+				g.logger.Errorf("Error: %v", err)
+				os.Exit(1)
+			}
+
+			for _, e := range entries {
+				target := filepath.Join(args[0], e.Path)
+				if !g.addResult(target, statusQueued) {
+					continue
+				}
+				g.mu.Lock()
+				g.updateRemote(target, gitRemote{Name: defaultRemote, URL: e.URL})
+				g.mu.Unlock()
+				remoteURL := e.URL
+				g.spawn(target, func(dir string) { g.mirrorRepository(dir, remoteURL, opts) })
+			}
+			g.wait()
+			g.printMirrorSummary()
+
+			switch {
+			case g.cancelled():
+				os.Exit(130)
+			case g.anyFailed():
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.manifest, "manifest", "", "YAML file listing the repositories to mirror")
+	cmd.Flags().BoolVar(&opts.fsck, "fsck", false, "Also check every mirror with git fsck")
+	cmd.MarkFlagRequired("manifest")
+
+	return cmd
+}
+
+// readManifest parses the manifest and fills in default paths.
+func readManifest(file string) ([]mirrorEntry, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var manifest mirrorManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+
+	paths := map[string]string{}
+	for i, e := range manifest.Repos {
+		if e.URL == "" {
+			return nil, fmt.Errorf("%s: entry %d has no url", file, i+1)
+		}
+		if e.Path == "" {
+			e.Path = mirrorPath(e.URL)
+		}
+		e.Path = filepath.Clean(e.Path)
+		if filepath.IsAbs(e.Path) || e.Path == ".." || strings.HasPrefix(e.Path, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%s: path %q of %s must stay below the backup directory", file, e.Path, e.URL)
+		}
+		if other, ok := paths[e.Path]; ok {
+			return nil, fmt.Errorf("%s: %s and %s both mirror to %s", file, other, e.URL, e.Path)
+		}
+		paths[e.Path] = e.URL
+		manifest.Repos[i] = e
+	}
+	return manifest.Repos, nil
+}
+
+// mirrorPath derives the backup location of a repository from its URL:
+// host and path for remote URLs, the base name for local paths. The result
+// always ends in .git like other bare repositories.
+func mirrorPath(rawURL string) string {
+	host, p := splitRemoteURL(rawURL)
+	if host != "" {
+		p = path.Join(host, p)
+	} else {
+		p = filepath.Base(strings.TrimSuffix(rawURL, "/"))
+	}
+	return filepath.FromSlash(strings.TrimSuffix(p, ".git") + ".git")
+}
+
+// mirrorRepository clones remoteURL as a bare mirror at dir, or updates the
+// mirror already there.
+func (g *GitPullCommand) mirrorRepository(dir, remoteURL string, opts mirrorOptions) {
+	g.mu.Lock()
+	g.updateStatus(dir, statusFetching)
+	g.updateStarted(dir, time.Now())
+	g.mu.Unlock()
+
+	status, output := g.updateMirror(dir, remoteURL)
+
+	if opts.fsck && status != statusFailed && status != statusAuthRequired {
+		fsckOutput, err := g.runner.Run(dir, "fsck", "--no-progress")
+		output = append(output, fsckOutput...)
+		if err != nil {
+			g.logger.Errorf("Corruption found in %s: %v", dir, err)
+			status = statusCorrupt
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.updateOutput(dir, string(output))
+	if r := g.find(dir); r != nil {
+		r.Duration = time.Since(r.Started)
+	}
+	if g.cancelled() && status == statusFailed {
+		status = statusCancelled
+	}
+	g.updateStatus(dir, status)
+}
+
+func (g *GitPullCommand) updateMirror(dir, remoteURL string) (string, []byte) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+			g.logger.Errorf("Error creating %s: %v", filepath.Dir(dir), err)
+			return statusFailed, []byte(err.Error())
+		}
+		g.logger.Infof("Cloning mirror of %s into %s", remoteURL, dir)
+		output, err := g.runner.Run(filepath.Dir(dir), "clone", "--mirror", remoteURL, filepath.Base(dir))
+		return g.mirrorStatus(dir, statusCloned, output, err)
+	}
+
+	if !isBareRepo(dir) {
+		err := fmt.Errorf("%s exists but is not a bare repository", dir)
+		g.logger.Errorf("Error: %v", err)
+		return statusFailed, []byte(err.Error())
+	}
+
+	before, _ := g.runner.Run(dir, "for-each-ref")
+	g.logger.Infof("Updating mirror %s", dir)
+	output, err := g.runner.Run(dir, "remote", "update", "--prune")
+	after, _ := g.runner.Run(dir, "for-each-ref")
+
+	status := statusUpToDate
+	if string(before) != string(after) {
+		status = statusUpdated
+	}
+	return g.mirrorStatus(dir, status, output, err)
+}
+
+func (g *GitPullCommand) mirrorStatus(dir, status string, output []byte, err error) (string, []byte) {
+	if err == nil {
+		return status, output
+	}
+	g.logger.Errorf("Error mirroring %s: %v", dir, err)
+	if isAuthError(output, err) {
+		return statusAuthRequired, output
+	}
+	return statusFailed, output
+}
+
+func (g *GitPullCommand) printMirrorSummary() {
+	rows := make([][]string, 0, len(g.summary))
+	for _, r := range g.summary {
+		rows = append(rows, []string{r.Dir, displayRemote(r), g.msg(r.Status), formatDuration(r.Duration)})
+	}
+
+	g.renderTable([]string{g.msg(msgDirectory), g.msg(msgRemote), g.msg(msgStatus), g.msg(msgDuration)}, rows)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// notifyOptions configures the summary posted when a run finishes.
+type notifyOptions struct {
+	webhook string
+	slack   string
+	on      string
+}
+
+const (
+	notifyOnFailure = "failure"
+	notifyOnAlways  = "always"
+)
+
+// notifyTimeout bounds each notification request so an unreachable endpoint
+// cannot hold up a cron job.
+const notifyTimeout = 10 * time.Second
+
+// notifyMaxFailures caps the failed repositories listed in a Slack message.
+const notifyMaxFailures = 20
+
+func (g *GitPullCommand) addNotifyFlags() {
+	flags := g.rootCmd.Flags()
+	flags.StringVar(&g.notify.webhook, "notify-webhook", "", "URL that receives a JSON summary when the run finishes")
+	flags.StringVar(&g.notify.slack, "notify-slack", "", "Slack incoming webhook URL that receives a text summary when the run finishes")
+	flags.StringVar(&g.notify.on, "notify-on", notifyOnFailure, "When to send notifications (options: failure, always)")
+}
+
+func (n notifyOptions) validate() error {
+	switch n.on {
+	case notifyOnFailure, notifyOnAlways:
+		return nil
+	}
+	return fmt.Errorf("invalid --notify-on %q (options: failure, always)", n.on)
+}
+
+func (n notifyOptions) enabled() bool {
+	return n.webhook != "" || n.slack != ""
+}
+
+// notification is the JSON body posted to --notify-webhook.
+type notification struct {
+	Root     string               `json:"root"`
+	Started  time.Time            `json:"started"`
+	Finished time.Time            `json:"finished"`
+	Duration string               `json:"duration"`
+	Total    int                  `json:"total"`
+	Counts   map[string]int       `json:"counts"`
+	Failed   []notificationResult `json:"failed"`
+}
+
+// notificationResult describes one repository that needs attention.
+type notificationResult struct {
+	Dir      string `json:"dir"`
+	Remote   string `json:"remote"`
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+}
+
+// newNotification summarizes the current run. Counts and failures use the
+// same selection as --only failed.
+func (g *GitPullCommand) newNotification(root string, started time.Time) notification {
+	finished := time.Now()
+	n := notification{
+		Root:     root,
+		Started:  started,
+		Finished: finished,
+		Duration: formatDuration(finished.Sub(started)),
+		Total:    len(g.summary),
+		Counts:   map[string]int{},
+		Failed:   []notificationResult{},
+	}
+
+	failed := viewOptions{only: onlyFailed}
+	for _, r := range g.summary {
+		n.Counts[r.Status]++
+		if failed.matches(r) {
+			n.Failed = append(n.Failed, notificationResult{
+				Dir:      r.Dir,
+				Remote:   r.Remote,
+				Status:   r.Status,
+				Duration: formatDuration(r.Duration),
+			})
+		}
+	}
+	return n
+}
+
+// sendNotifications posts the run summary to the configured endpoints.
+// Delivery problems are logged rather than failing the run.
+func (g *GitPullCommand) sendNotifications(root string, started time.Time) {
+	if !g.notify.enabled() {
+		return
+	}
+
+	n := g.newNotification(root, started)
+	if g.notify.on == notifyOnFailure && len(n.Failed) == 0 {
+		g.logger.Debugf("No failures; skipping notifications")
+		return
+	}
+
+	if g.notify.webhook != "" {
+		if err := postJSON(g.notify.webhook, n); err != nil {
+			g.logger.Errorf("Error sending webhook notification: %v", err)
+		}
+	}
+
+	if g.notify.slack != "" {
+		msg := struct {
+			Text string `json:"text"`
+		}{Text: g.slackText(n)}
+		if err := postJSON(g.notify.slack, msg); err != nil {
+			g.logger.Errorf("Error sending Slack notification: %v", err)
+		}
+	}
+}
+
+// slackText renders the summary as a short Slack message.
+func (g *GitPullCommand) slackText(n notification) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "gitpull %s: %d repositories in %s", n.Root, n.Total, n.Duration)
+
+	statuses := make([]string, 0, len(n.Counts))
+	for status := range n.Counts {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+
+	parts := make([]string, len(statuses))
+	for i, status := range statuses {
+		parts[i] = fmt.Sprintf("%d %s", n.Counts[status], g.msg(status))
+	}
+	fmt.Fprintf(&b, " (%s)", strings.Join(parts, ", "))
+
+	for i, r := range n.Failed {
+		if i == notifyMaxFailures {
+			fmt.Fprintf(&b, "\n…and %d more", len(n.Failed)-i)
+			break
+		}
+		fmt.Fprintf(&b, "\n• `%s`: %s", r.Dir, g.msg(r.Status))
+	}
+	return b.String()
+}
+
+func postJSON(url string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: notifyTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
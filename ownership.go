@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+// --add-safe-directory modes for repositories owned by other users.
+const (
+	safeDirectoryGlobal = "global"
+	safeDirectoryRun    = "run"
+)
+
+// ownershipPattern matches git's refusal to open a repository owned by
+// another user.
+var ownershipPattern = regexp.MustCompile(`detected dubious ownership`)
+
+// safeDirectoryOptions hold the --add-safe-directory mode. Concurrent pulls
+// must not write the global git config at once, as git gives up when
+// ~/.gitconfig.lock is held, so mu serializes the writes and guards the
+// paths already added.
+type safeDirectoryOptions struct {
+	mode  string
+	mu    sync.Mutex
+	added map[string]bool
+}
+
+func (g *GitPullCommand) addOwnershipFlags() {
+	g.rootCmd.PersistentFlags().StringVar(&g.safeDirectory.mode, "add-safe-directory", "", "Trust repositories owned by other users (options: global adds them to safe.directory in ~/.gitconfig, run trusts them for this run only)")
+}
+
+func validateSafeDirectory(mode string) error {
+	switch mode {
+	case "", safeDirectoryGlobal, safeDirectoryRun:
+		return nil
+	}
+	return fmt.Errorf("invalid --add-safe-directory %q (options: %s, %s)", mode, safeDirectoryGlobal, safeDirectoryRun)
+}
+
+// distrusted reports whether git refuses to open dir because another user
+// owns it. Without the git binary nothing is distrusted, like go-git.
+func (g *GitPullCommand) distrusted(dir string) bool {
+	output, err := g.runner.Run(dir, "rev-parse", "--git-dir")
+	return err != nil && ownershipPattern.Match(output)
+}
+
+// trustDirectory marks dir as safe according to --add-safe-directory. It
+// returns the status to finish the pull with when dir stays untrusted, or
+// "" when git may be retried.
+func (g *GitPullCommand) trustDirectory(dir string) (string, []byte) {
+	// git compares safe.directory with the real path of the working tree.
+	path := filepath.ToSlash(repoKey(dir))
+
+	switch g.safeDirectory.mode {
+	case safeDirectoryGlobal:
+		return g.addSafeDirectory(dir, path)
+	case safeDirectoryRun:
+		g.settings.trust(dir, path)
+		g.logger.Infof("Trusting %s for this run", path)
+	default:
+		g.logger.Errorf("Repository %s is owned by another user", dir)
+		return statusOwnership, []byte(fmt.Sprintf("git refuses %s because another user owns it: pass --add-safe-directory or run git config --global --add safe.directory %s\n", dir, path))
+	}
+	return "", nil
+}
+
+// addSafeDirectory adds path to safe.directory in the global git config
+// once per run.
+func (g *GitPullCommand) addSafeDirectory(dir, path string) (string, []byte) {
+	o := &g.safeDirectory
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.added[path] {
+		return "", nil
+	}
+
+	if output, err := g.runner.Run(dir, "config", "--global", "--add", "safe.directory", path); err != nil {
+		g.logger.Errorf("Error adding %s to safe.directory: %v", path, err)
+		return statusFailed, output
+	}
+	if o.added == nil {
+		o.added = map[string]bool{}
+	}
+	o.added[path] = true
+	g.logger.Infof("Added %s to safe.directory in the global git config", path)
+	return "", nil
+}
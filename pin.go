@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// pinFor returns the ref dir is pinned to in the config file, or "" when it
+// is pulled as usual. Later entries win, e.g.
+//
+//	repos:
+//	  - path: ~/tools/protoc
+//	    pin: v2.3.1
+func (g *GitPullCommand) pinFor(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = dir
+	}
+	pin := ""
+	for _, o := range g.settings.perPath {
+		if o.Pin != "" && o.matches(abs) {
+			pin = o.Pin
+		}
+	}
+	return pin
+}
+
+// checkoutPin fetches dir from remote and detaches HEAD at the pinned tag,
+// branch or commit instead of merging.
+func (g *GitPullCommand) checkoutPin(dir, remote, pin, oldHead string) (string, []byte) {
+	g.logger.Infof("Checking out %s in pinned repository: %s", pin, dir)
+	output, err := g.client.Fetch(dir, remote, g.fetch)
+	g.recordReceived(dir, output)
+	if err != nil {
+		g.logger.Errorf("Error executing git fetch: %v", err)
+		if isAuthError(output, err) {
+			return statusAuthRequired, output
+		}
+		return statusFailed, output
+	}
+
+	target, fetched, err := g.resolvePin(dir, remote, pin)
+	output = append(output, fetched...)
+	if err != nil {
+		g.logger.Errorf("Error resolving pin %s of %s: %v", pin, dir, err)
+		return statusFailed, append(output, []byte(err.Error()+"\n")...)
+	}
+	if target == oldHead {
+		return statusPinned, output
+	}
+
+	if st, err := g.client.Status(dir); err == nil && st.Dirty {
+		g.logger.Errorf("Not moving %s to %s: the working tree has changes", dir, pin)
+		return statusFailed, append(output, []byte(fmt.Sprintf("not moving to the pinned %s: the working tree has changes\n", pin))...)
+	}
+	checkout, err := g.runner.Run(dir, "checkout", "--quiet", "--detach", target)
+	output = append(output, checkout...)
+	if err != nil {
+		g.logger.Errorf("Error checking out %s in %s: %v", pin, dir, err)
+		return statusFailed, output
+	}
+
+	if oldHead != "" {
+		g.countChanges(dir, oldHead, target)
+	}
+	return statusPinnedMoved, output
+}
+
+// resolvePin finds the commit a pin names: the remote's branch of that name
+// first, so branch pins follow the remote, then tags and commits. Commits
+// the fetch did not bring in are fetched by name.
+func (g *GitPullCommand) resolvePin(dir, remote, pin string) (string, []byte, error) {
+	for _, ref := range []string{"refs/remotes/" + remote + "/" + pin, "refs/tags/" + pin, pin} {
+		if sha, err := g.runner.Run(dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err == nil {
+			return strings.TrimSpace(string(sha)), nil, nil
+		}
+	}
+
+	output, err := g.runner.Run(dir, "fetch", remote, pin)
+	if err != nil {
+		return "", output, fmt.Errorf("%s is not a branch, tag or commit of %s", pin, remote)
+	}
+	sha, err := g.runner.Run(dir, "rev-parse", "--verify", "--quiet", "FETCH_HEAD^{commit}")
+	if err != nil {
+		return "", output, fmt.Errorf("%s does not name a commit", pin)
+	}
+	return strings.TrimSpace(string(sha)), output, nil
+}
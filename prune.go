@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+)
+
+// pruneOptions controls the cleanup performed after a successful pull.
+type pruneOptions struct {
+	remote bool
+	local  bool
+}
+
+func (g *GitPullCommand) addPruneFlags() {
+	flags := g.rootCmd.Flags()
+	flags.BoolVar(&g.prune.remote, "prune", false, "Remove stale remote-tracking branches after a successful pull")
+	flags.BoolVar(&g.prune.local, "prune-local", false, "With --prune, also delete local branches whose upstream is gone")
+}
+
+// pruneRepository removes stale refs of remote from dir and returns how many were
+// pruned.
+func (g *GitPullCommand) pruneRepository(dir, remote string) int {
+	output, err := g.runner.Run(dir, "remote", "prune", remote)
+	if err != nil {
+		g.logger.Errorf("Error pruning remote branches in %s: %v", dir, err)
+		return 0
+	}
+	pruned := strings.Count(string(output), "[pruned]")
+
+	if g.prune.local {
+		pruned += g.pruneLocalBranches(dir)
+	}
+
+	return pruned
+}
+
+// pruneLocalBranches deletes local branches whose upstream no longer
+// exists. Branches with unmerged commits are kept, as with `git branch -d`.
+func (g *GitPullCommand) pruneLocalBranches(dir string) int {
+	output, err := g.runner.Run(dir, "for-each-ref", "--format=%(refname:short) %(upstream:track)", "refs/heads")
+	if err != nil {
+		g.logger.Errorf("Error listing branches in %s: %v", dir, err)
+		return 0
+	}
+
+	deleted := 0
+	for _, line := range strings.Split(string(output), "\n") {
+		branch, track, ok := strings.Cut(line, " ")
+		if !ok || track != "[gone]" {
+			continue
+		}
+
+		if out, err := g.runner.Run(dir, "branch", "-d", branch); err != nil {
+			g.logger.Warnf("Keeping branch %s in %s: %s", branch, dir, strings.TrimSpace(string(out)))
+			continue
+		}
+		g.logger.Infof("Deleted branch %s in %s", branch, dir)
+		deleted++
+	}
+
+	return deleted
+}
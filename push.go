@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// pushOptions are the flags of the push subcommand.
+type pushOptions struct {
+	dryRun         bool
+	forceWithLease bool
+}
+
+func (g *GitPullCommand) newPushCommand() *cobra.Command {
+	var opts pushOptions
+
+	cmd := &cobra.Command{
+		Use:   "push <dir>",
+		Short: "Push repositories that are ahead of their upstream",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := g.setupClient(); err != nil {
+				g.logger.Errorf("Error: %v", err)
+				os.Exit(1)
+			}
+
+			g.forEachRepo(args[0], func(dir string) { g.pushRepository(dir, opts) })
+			g.printPushSummary()
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Only report what would be pushed")
+	cmd.Flags().BoolVar(&opts.forceWithLease, "force-with-lease", false, "Push with --force-with-lease")
+
+	return cmd
+}
+
+func (g *GitPullCommand) pushRepository(dir string, opts pushOptions) {
+	g.mu.Lock()
+	g.updateStatus(dir, statusPushing)
+	g.mu.Unlock()
+
+	if remote, err := g.resolveRemote(dir); err == nil {
+		g.mu.Lock()
+		g.updateRemote(dir, remote)
+		g.mu.Unlock()
+	}
+
+	ahead, err := g.client.Ahead(dir)
+	if err != nil {
+		status := statusFailed
+		if errors.Is(err, errNoUpstream) {
+			status = statusNoUpstream
+		} else {
+			g.logger.Errorf("Error comparing %s with its upstream: %v", dir, err)
+		}
+		g.mu.Lock()
+		g.updateStatus(dir, status)
+		g.updateOutput(dir, err.Error())
+		g.mu.Unlock()
+		return
+	}
+
+	g.mu.Lock()
+	g.updateCommits(dir, ahead)
+	g.mu.Unlock()
+
+	switch {
+	case ahead == 0:
+		g.mu.Lock()
+		g.updateStatus(dir, statusNothingToPush)
+		g.mu.Unlock()
+		return
+	case opts.dryRun:
+		g.mu.Lock()
+		g.updateStatus(dir, statusWouldPush)
+		g.mu.Unlock()
+		return
+	}
+
+	g.logger.Infof("Performing git push for repository: %s", dir)
+	output, err := g.client.Push(dir, opts.forceWithLease)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.updateOutput(dir, string(output))
+	if err != nil {
+		g.logger.Errorf("Error executing git push: %v", err)
+		status := statusFailed
+		if isAuthError(output, err) {
+			status = statusAuthRequired
+		}
+		g.updateStatus(dir, status)
+		return
+	}
+	g.updateStatus(dir, statusPushed)
+}
+
+func (g *GitPullCommand) printPushSummary() {
+	rows := make([][]string, 0, len(g.summary))
+	for _, r := range g.summary {
+		rows = append(rows, []string{r.Dir, displayRemote(r), g.msg(r.Status), strconv.Itoa(r.Commits)})
+	}
+
+	g.renderTable([]string{g.msg(msgDirectory), g.msg(msgRemote), g.msg(msgStatus), g.msg(msgCommits)}, rows)
+}
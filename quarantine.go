@@ -0,0 +1,97 @@
+package main
+
+// quarantineOptions controls when repeatedly failing repositories are
+// skipped. A repository that failed its last `after` attempts is pulled
+// only on every `every`th run.
+type quarantineOptions struct {
+	after int
+	every int
+}
+
+func (g *GitPullCommand) addQuarantineFlags() {
+	flags := g.rootCmd.Flags()
+	flags.IntVar(&g.quarantine.after, "quarantine-after", 3, "Quarantine repositories that failed this many runs in a row (0 disables)")
+	flags.IntVar(&g.quarantine.every, "quarantine-retry-every", 5, "Retry quarantined repositories on every Nth run")
+}
+
+// loadQuarantine marks the repositories to skip this run from history.
+func (g *GitPullCommand) loadQuarantine() {
+	if g.quarantine.after <= 0 || g.historyDir == "" {
+		return
+	}
+
+	every := g.quarantine.every
+	if every < 1 {
+		every = 1
+	}
+
+	// Enough runs to see `after` failed attempts separated by quarantined runs.
+	records, err := g.historyStore().load(g.quarantine.after * every)
+	if err != nil {
+		g.logger.Errorf("Error reading run history: %v", err)
+		return
+	}
+
+	g.quarantined = map[string]bool{}
+	for dir, statuses := range statusesByRepo(records) {
+		if isQuarantined(statuses, g.quarantine.after, every) {
+			g.quarantined[dir] = true
+		}
+	}
+
+	if len(g.quarantined) > 0 {
+		g.logger.Infof("%d repositories are quarantined this run", len(g.quarantined))
+	}
+}
+
+// statusesByRepo collects each repository's statuses, newest first.
+func statusesByRepo(records []runRecord) map[string][]string {
+	statuses := map[string][]string{}
+	for _, rec := range records {
+		for _, e := range rec.Results {
+			statuses[e.Dir] = append(statuses[e.Dir], e.Status)
+		}
+	}
+	return statuses
+}
+
+// isQuarantined decides from newest-first statuses whether a repository
+// sits out this run. Runs it sat out are ignored when counting failures.
+func isQuarantined(statuses []string, after, every int) bool {
+	skipped, failures := 0, 0
+	counting := true
+	for _, status := range statuses {
+		if status == statusQuarantined {
+			if counting {
+				skipped++
+			}
+			continue
+		}
+		counting = false
+
+		if !isFailure(status) {
+			break
+		}
+		failures++
+	}
+
+	return failures >= after && skipped < every-1
+}
+
+// isFailure reports whether a final status means the pull did not work.
+func isFailure(status string) bool {
+	switch status {
+	case statusFailed, statusAuthRequired, statusCorrupt, statusUnreachable, statusHostKey, statusOwnership:
+		return true
+	}
+	return false
+}
+
+// isSuccess reports whether a final status means the repository is current.
+func isSuccess(status string) bool {
+	switch status {
+	case statusUpdated, statusUpToDate, statusFetched, statusPinned, statusPinnedMoved:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func (g *GitPullCommand) newReleasesCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "releases <dir>",
+		Short: "Fetch tags and report repositories whose latest tag changed",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			stopSignals := g.handleSignals()
+			defer stopSignals()
+
+			if err := g.setupClient(); err != nil {
+				g.logger.Errorf("Error: %v", err)
+				os.Exit(1)
+			}
+			if err := g.requireGit("gitpull releases"); err != nil {
+				g.logger.Errorf("Error: %v", err)
+				os.Exit(1)
+			}
+
+			g.forEachRepo(args[0], g.trackRelease)
+			g.printReleasesSummary()
+
+			failed := viewOptions{only: onlyFailed}
+			for _, r := range g.summary {
+				if failed.matches(r) {
+					os.Exit(1)
+				}
+			}
+		},
+	}
+}
+
+// trackRelease fetches the tags of dir without touching its branches and
+// compares the latest tag before and after.
+func (g *GitPullCommand) trackRelease(dir string) {
+	g.mu.Lock()
+	g.updateStatus(dir, statusFetching)
+	g.mu.Unlock()
+
+	remote, err := g.resolveRemote(dir)
+	if err != nil {
+		g.logger.Errorf("Error selecting remote for %s: %v", dir, err)
+		g.mu.Lock()
+		g.updateStatus(dir, statusFailed)
+		g.updateOutput(dir, err.Error())
+		g.mu.Unlock()
+		return
+	}
+
+	before := g.latestTag(dir)
+
+	opts := g.fetch
+	opts.tags = true
+	g.logger.Infof("Fetching tags for repository: %s", dir)
+	output, err := g.client.Fetch(dir, remote.Name, opts)
+	after := ""
+	if err == nil {
+		after = g.latestTag(dir)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.updateRemote(dir, remote)
+	g.updateOutput(dir, string(output))
+	if err != nil {
+		g.logger.Errorf("Error fetching tags for %s: %v", dir, err)
+		status := statusFailed
+		if isAuthError(output, err) {
+			status = statusAuthRequired
+		}
+		g.updateStatus(dir, status)
+		return
+	}
+
+	if r := g.find(dir); r != nil {
+		r.TagBefore = before
+		r.TagAfter = after
+	}
+	if after != "" && after != before {
+		g.updateStatus(dir, statusNewRelease)
+	} else {
+		g.updateStatus(dir, statusNoNewRelease)
+	}
+}
+
+// latestTag returns the most recently created tag of dir, or "" when it has
+// none.
+func (g *GitPullCommand) latestTag(dir string) string {
+	output, err := g.runner.Run(dir, "for-each-ref", "--sort=-creatordate", "--count=1", "--format=%(refname:short)", "refs/tags")
+	if err != nil {
+		g.logger.Debugf("Could not list tags in %s: %v", dir, err)
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// printReleasesSummary lists repositories with a new release first.
+func (g *GitPullCommand) printReleasesSummary() {
+	rows := make([][]string, 0, len(g.summary))
+	var rest [][]string
+	for _, r := range g.summary {
+		row := []string{r.Dir, displayRemote(r), g.msg(r.Status), r.TagBefore, r.TagAfter}
+		if r.Status == statusNewRelease {
+			rows = append(rows, row)
+		} else {
+			rest = append(rest, row)
+		}
+	}
+
+	g.renderTable([]string{g.msg(msgDirectory), g.msg(msgRemote), g.msg(msgStatus), g.msg(msgTagBefore), g.msg(msgTagAfter)}, append(rows, rest...))
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// remoteOptions chooses which remote a repository is pulled from when it
+// has several, e.g. a fork with origin and upstream, and how its URL is
+// rewritten and checked before pulling.
+type remoteOptions struct {
+	name           string
+	preferUpstream bool
+	rewrite        []string
+	check          bool
+	checkTimeout   time.Duration
+}
+
+const (
+	defaultRemote  = "origin"
+	upstreamRemote = "upstream"
+)
+
+func (g *GitPullCommand) addRemoteFlags() {
+	flags := g.rootCmd.PersistentFlags()
+	flags.StringVar(&g.remote.name, "remote", defaultRemote, "Remote to pull from")
+	flags.BoolVar(&g.remote.preferUpstream, "prefer-upstream", false, "Pull from the upstream remote in repositories that have one")
+	flags.StringArrayVar(&g.remote.rewrite, "rewrite-remote", nil, "Rewrite remote URLs starting with old to start with new before pulling, as old=new (repeatable)")
+	flags.BoolVar(&g.remote.check, "check-remote", false, "Check that the remote answers git ls-remote before pulling")
+	flags.DurationVar(&g.remote.checkTimeout, "check-remote-timeout", 10*time.Second, "How long --check-remote waits for a remote")
+}
+
+func (o remoteOptions) validate() error {
+	for _, rule := range o.rewrite {
+		if old, _, ok := strings.Cut(rule, "="); !ok || old == "" {
+			return fmt.Errorf("invalid --rewrite-remote %q: expected old=new", rule)
+		}
+	}
+	if o.checkTimeout <= 0 {
+		return fmt.Errorf("--check-remote-timeout must be positive")
+	}
+	return nil
+}
+
+// resolveRemote returns the remote dir is pulled from. Without an explicit
+// --remote, repositories lacking origin fall back to their first remote.
+func (g *GitPullCommand) resolveRemote(dir string) (gitRemote, error) {
+	remotes, err := g.client.Remotes(dir)
+	if err != nil {
+		return gitRemote{}, fmt.Errorf("listing remotes: %v", err)
+	}
+	if len(remotes) == 0 {
+		return gitRemote{}, fmt.Errorf("no remote configured")
+	}
+
+	byName := map[string]gitRemote{}
+	for _, r := range remotes {
+		byName[r.Name] = r
+	}
+
+	if g.remote.preferUpstream {
+		if r, ok := byName[upstreamRemote]; ok {
+			return r, nil
+		}
+	}
+	if r, ok := byName[g.remote.name]; ok {
+		return r, nil
+	}
+	if g.rootCmd.PersistentFlags().Changed("remote") {
+		return gitRemote{}, fmt.Errorf("no remote named %q", g.remote.name)
+	}
+	return remotes[0], nil
+}
+
+// displayRemote labels the remote used in summaries; the name is only shown
+// when it is not origin.
+func displayRemote(r *repoResult) string {
+	if r.RemoteName == "" || r.RemoteName == defaultRemote {
+		return r.Remote
+	}
+	return fmt.Sprintf("%s (%s)", r.Remote, r.RemoteName)
+}
+
+// rewriteRemote applies the first --rewrite-remote rule matching the URL of
+// remote and stores the new URL in the repository's config.
+func (g *GitPullCommand) rewriteRemote(dir string, remote gitRemote) (gitRemote, error) {
+	for _, rule := range g.remote.rewrite {
+		old, replacement, _ := strings.Cut(rule, "=")
+		if !strings.HasPrefix(remote.URL, old) {
+			continue
+		}
+		url := replacement + strings.TrimPrefix(remote.URL, old)
+		if output, err := g.runner.Run(dir, "remote", "set-url", remote.Name, url); err != nil {
+			return remote, fmt.Errorf("rewriting %s to %s: %v: %s", remote.URL, url, err, strings.TrimSpace(string(output)))
+		}
+		g.logger.Infof("Rewrote remote %s of %s from %s to %s", remote.Name, dir, remote.URL, url)
+		remote.URL = url
+		break
+	}
+	return remote, nil
+}
+
+// contextRunner is implemented by runners that can bound a command with a
+// context of its own.
+type contextRunner interface {
+	withContext(ctx context.Context) gitRunner
+}
+
+// checkRemote asks remote for its HEAD and returns the status to finish the
+// pull with when it does not answer in time, or "" when it is reachable.
+func (g *GitPullCommand) checkRemote(dir string, remote gitRemote) (string, []byte) {
+	runner := g.runner
+	if r, ok := runner.(contextRunner); ok {
+		ctx, cancel := context.WithTimeout(g.ctx, g.remote.checkTimeout)
+		defer cancel()
+		runner = r.withContext(ctx)
+	}
+
+	output, err := runner.Run(dir, "ls-remote", "--exit-code", remote.Name, "HEAD")
+	switch {
+	case err == nil:
+		return "", nil
+	case exitCode(err) == 2:
+		// Reachable, but without a HEAD, such as an empty repository.
+		return "", nil
+	case isAuthError(output, err):
+		return statusAuthRequired, output
+	}
+	g.logger.Errorf("Remote %s of %s is unreachable: %v", remote.URL, dir, err)
+	return statusUnreachable, output
+}
+
+// splitRemoteURL returns the host and path of a remote URL, accepting the
+// scp-like git@host:path syntax. Local paths have no host.
+func splitRemoteURL(rawURL string) (host, path string) {
+	if u, err := url.Parse(rawURL); err == nil && (u.Host != "" || u.Scheme == "file") {
+		return u.Hostname(), u.Path
+	}
+	if strings.Contains(rawURL, ":") && !strings.HasPrefix(rawURL, "/") && !isWindowsDrive(rawURL) {
+		host, path, _ = strings.Cut(rawURL, ":")
+		if _, h, ok := strings.Cut(host, "@"); ok {
+			host = h
+		}
+		return host, path
+	}
+	return "", rawURL
+}
+
+// isWindowsDrive reports whether path starts with a drive letter, e.g. C:\.
+func isWindowsDrive(path string) bool {
+	return len(path) >= 2 && path[1] == ':' && (len(path) == 2 || path[2] == '\\' || path[2] == '/') &&
+		('a' <= path[0] && path[0] <= 'z' || 'A' <= path[0] && path[0] <= 'Z')
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Issue types the repair subcommand detects, in the order they are fixed:
+// later fixes need the index lock and a finished merge.
+const (
+	issueLock    = "lock"
+	issueMerge   = "merge"
+	issueHead    = "head"
+	issueShallow = "shallow"
+)
+
+var repairIssues = []string{issueLock, issueMerge, issueHead, issueShallow}
+
+// repairOptions are the flags of the repair subcommand.
+type repairOptions struct {
+	fix     []string
+	dryRun  bool
+	lockAge time.Duration
+}
+
+// repairIssue is a problem found in a repository.
+type repairIssue struct {
+	kind   string
+	detail string
+	fixed  bool
+}
+
+// interruptedOps maps the state files git leaves behind to the command
+// that abandons the operation.
+var interruptedOps = []struct {
+	file string
+	args []string
+}{
+	{"rebase-merge", []string{"rebase", "--abort"}},
+	{"rebase-apply", []string{"rebase", "--abort"}},
+	{"MERGE_HEAD", []string{"merge", "--abort"}},
+	{"CHERRY_PICK_HEAD", []string{"cherry-pick", "--abort"}},
+	{"REVERT_HEAD", []string{"revert", "--abort"}},
+}
+
+func (g *GitPullCommand) newRepairCommand() *cobra.Command {
+	opts := repairOptions{fix: repairIssues, lockAge: time.Hour}
+
+	cmd := &cobra.Command{
+		Use:   "repair <dir>",
+		Short: "Find and fix shallow clones, broken HEADs, interrupted merges and stale index locks",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, kind := range opts.fix {
+				if !contains(repairIssues, kind) {
+					g.logger.Errorf("Error: invalid --fix %q (options: %s)", kind, strings.Join(repairIssues, ", "))
+					os.Exit(1)
+				}
+			}
+
+			stopSignals := g.handleSignals()
+			defer stopSignals()
+
+			if err := g.setupClient(); err != nil {
+				g.logger.Errorf("Error: %v", err)
+				os.Exit(1)
+			}
+			if err := g.requireGit("gitpull repair"); err != nil {
+				g.logger.Errorf("Error: %v", err)
+				os.Exit(1)
+			}
+
+			issues := map[string][]repairIssue{}
+			g.forEachRepo(args[0], func(dir string) {
+				found := g.repairRepository(dir, opts)
+				g.mu.Lock()
+				issues[dir] = found
+				g.mu.Unlock()
+			})
+			g.printRepairSummary(issues)
+
+			failed := viewOptions{only: onlyFailed}
+			for _, r := range g.summary {
+				if failed.matches(r) {
+					os.Exit(1)
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&opts.fix, "fix", opts.fix, "Issue types to fix; others are only reported (options: "+strings.Join(repairIssues, ", ")+")")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Only report issues, fix nothing")
+	cmd.Flags().DurationVar(&opts.lockAge, "lock-age", opts.lockAge, "Remove index.lock files older than this; younger ones may belong to a running git")
+
+	return cmd
+}
+
+func contains(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
+
+// repairRepository detects the issues of dir and fixes those selected by
+// --fix.
+func (g *GitPullCommand) repairRepository(dir string, opts repairOptions) []repairIssue {
+	g.mu.Lock()
+	g.updateStatus(dir, statusRepairing)
+	g.updateStarted(dir, time.Now())
+	kind := g.find(dir).Kind
+	g.mu.Unlock()
+
+	unlock := g.lockRepo(dir)
+	defer unlock()
+
+	var issues []repairIssue
+	var output []byte
+	status := statusHealthy
+	for _, issueKind := range repairIssues {
+		if kind == kindBare && issueKind != issueShallow {
+			continue
+		}
+		detail, fix := g.detectIssue(dir, issueKind, opts)
+		if fix == nil {
+			continue
+		}
+		issue := repairIssue{kind: issueKind, detail: detail}
+		if status == statusHealthy {
+			status = statusNeedsRepair
+		}
+
+		if !opts.dryRun && contains(opts.fix, issueKind) {
+			g.logger.Infof("Fixing %s in %s: %s", issueKind, dir, detail)
+			fixOutput, err := fix()
+			output = append(output, fixOutput...)
+			if err != nil {
+				g.logger.Errorf("Error fixing %s in %s: %v", issueKind, dir, err)
+				output = append(output, []byte(err.Error()+"\n")...)
+				status = statusFailed
+			} else {
+				issue.fixed = true
+			}
+		}
+		issues = append(issues, issue)
+	}
+
+	if status == statusNeedsRepair && allFixed(issues) {
+		status = statusRepaired
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.updateOutput(dir, string(output))
+	if r := g.find(dir); r != nil {
+		r.Duration = time.Since(r.Started)
+	}
+	g.updateStatus(dir, status)
+	return issues
+}
+
+func allFixed(issues []repairIssue) bool {
+	for _, issue := range issues {
+		if !issue.fixed {
+			return false
+		}
+	}
+	return true
+}
+
+// detectIssue checks dir for one issue type. It returns a description and
+// the fix, or a nil fix when the repository does not have the issue.
+func (g *GitPullCommand) detectIssue(dir, kind string, opts repairOptions) (string, func() ([]byte, error)) {
+	gitDir := worktreeGitDir(dir)
+
+	switch kind {
+	case issueLock:
+		lock := filepath.Join(gitDir, "index.lock")
+		info, err := os.Stat(lock)
+		if err != nil || time.Since(info.ModTime()) < opts.lockAge {
+			return "", nil
+		}
+		detail := fmt.Sprintf("index.lock is %s old", time.Since(info.ModTime()).Round(time.Second))
+		return detail, func() ([]byte, error) { return nil, os.Remove(lock) }
+
+	case issueMerge:
+		for _, op := range interruptedOps {
+			if _, err := os.Stat(filepath.Join(gitDir, op.file)); err == nil {
+				args := op.args
+				return "interrupted " + args[0], func() ([]byte, error) { return g.runner.Run(dir, args...) }
+			}
+		}
+
+	case issueHead:
+		if _, err := g.runner.Run(dir, "rev-parse", "--verify", "--quiet", "HEAD^{commit}"); err == nil {
+			return "", nil
+		}
+		// A new repository without commits has an unborn HEAD, which is
+		// fine; a HEAD that points nowhere while refs exist is broken.
+		if refs, err := g.runner.Run(dir, "for-each-ref", "--count=1"); err == nil && strings.TrimSpace(string(refs)) == "" {
+			return "", nil
+		}
+		return "HEAD does not point to a commit", func() ([]byte, error) { return g.repairHead(dir) }
+
+	case issueShallow:
+		output, err := g.runner.Run(dir, "rev-parse", "--is-shallow-repository")
+		if err != nil || strings.TrimSpace(string(output)) != "true" {
+			return "", nil
+		}
+		return "shallow clone", func() ([]byte, error) {
+			remote, err := g.resolveRemote(dir)
+			if err != nil {
+				return nil, err
+			}
+			return g.runner.Run(dir, "fetch", "--unshallow", remote.Name)
+		}
+	}
+	return "", nil
+}
+
+// worktreeGitDir returns the git directory of the worktree at dir, where
+// git keeps the index and the state of merges and rebases.
+func worktreeGitDir(dir string) string {
+	gitPath := gitDirOf(dir)
+	if gitdir, ok := readGitfile(gitPath); ok {
+		return gitdir
+	}
+	return gitPath
+}
+
+// repairHead points HEAD back at a branch: the one it names when the remote
+// still has it, the remote's default branch otherwise. A missing branch is
+// recreated from the remote, and only the index is reset, so files in the
+// working tree are kept.
+func (g *GitPullCommand) repairHead(dir string) ([]byte, error) {
+	// git does not recognize a repository whose HEAD file is garbage at
+	// all. A placeholder branch makes it usable and is then replaced by
+	// the default branch, as the remote does not have it.
+	if _, err := g.runner.Run(dir, "rev-parse", "--git-dir"); err != nil {
+		head := filepath.Join(worktreeGitDir(dir), "HEAD")
+		if err := os.WriteFile(head, []byte("ref: refs/heads/gitpull-repair\n"), 0o644); err != nil {
+			return nil, err
+		}
+	}
+
+	remote, err := g.resolveRemote(dir)
+	if err != nil {
+		return nil, err
+	}
+	output, err := g.runner.Run(dir, "fetch", remote.Name)
+	if err != nil {
+		return output, err
+	}
+
+	branch := ""
+	if ref, err := g.runner.Run(dir, "symbolic-ref", "--quiet", "--short", "HEAD"); err == nil {
+		branch = strings.TrimSpace(string(ref))
+	}
+	if _, err := g.runner.Run(dir, "rev-parse", "--verify", "--quiet", "refs/remotes/"+remote.Name+"/"+branch); branch == "" || err != nil {
+		if branch, err = g.defaultBranch(dir, remote.Name); err != nil {
+			return output, err
+		}
+	}
+
+	steps := [][]string{{"symbolic-ref", "HEAD", "refs/heads/" + branch}, {"reset", "--quiet"}}
+	// An existing branch may hold unpushed commits, so only a missing one
+	// is recreated from the remote.
+	if _, err := g.runner.Run(dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch+"^{commit}"); err != nil {
+		steps = append([][]string{{"update-ref", "refs/heads/" + branch, "refs/remotes/" + remote.Name + "/" + branch}}, steps...)
+	}
+	for _, args := range steps {
+		out, err := g.runner.Run(dir, args...)
+		output = append(output, out...)
+		if err != nil {
+			return output, err
+		}
+	}
+	return output, nil
+}
+
+func (g *GitPullCommand) printRepairSummary(issues map[string][]repairIssue) {
+	rows := make([][]string, 0, len(g.summary))
+	for _, r := range g.summary {
+		var found []string
+		for _, issue := range issues[r.Dir] {
+			text := issue.detail
+			if issue.fixed {
+				text += " (" + g.msg(msgFixed) + ")"
+			}
+			found = append(found, text)
+		}
+		rows = append(rows, []string{r.Dir, g.msg(r.Status), strings.Join(found, ", "), formatDuration(r.Duration)})
+	}
+
+	g.renderTable([]string{g.msg(msgDirectory), g.msg(msgStatus), g.msg(msgIssues), g.msg(msgDuration)}, rows)
+}
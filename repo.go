@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Kinds of repository layouts recognized during discovery.
+const (
+	kindNone     = ""
+	kindRepo     = "repo"
+	kindWorktree = "worktree"
+	kindBare     = "bare"
+)
+
+// detectRepo returns the kind of repository rooted at dir, or kindNone.
+func detectRepo(dir string) string {
+	gitPath := filepath.Join(dir, ".git")
+	info, err := os.Stat(gitPath)
+	switch {
+	case err == nil && info.IsDir():
+		return kindRepo
+	case err == nil:
+		return gitfileKind(gitPath)
+	case isBareRepo(dir):
+		return kindBare
+	}
+	return kindNone
+}
+
+// gitfileKind classifies a .git file. Linked worktrees point into the
+// main repository's worktrees directory; anything else with a gitdir line,
+// such as a submodule checkout, is pulled like a normal repository.
+func gitfileKind(path string) string {
+	gitdir, ok := readGitfile(path)
+	if !ok {
+		return kindNone
+	}
+	if sameName(filepath.Base(filepath.Dir(gitdir)), "worktrees") {
+		return kindWorktree
+	}
+	return kindRepo
+}
+
+// readGitfile returns the directory named by a "gitdir: <path>" file.
+func readGitfile(path string) (string, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return "", false
+	}
+	gitdir, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "gitdir: ")
+	if !ok || gitdir == "" {
+		return "", false
+	}
+	if !filepath.IsAbs(gitdir) {
+		gitdir = filepath.Join(filepath.Dir(path), gitdir)
+	}
+	return filepath.Clean(gitdir), true
+}
+
+// isBareRepo reports whether dir has the HEAD, objects and refs entries of
+// a git directory. HEAD is checked first as it rules out most directories
+// with a single stat.
+func isBareRepo(dir string) bool {
+	if info, err := os.Stat(filepath.Join(dir, "HEAD")); err != nil || info.IsDir() {
+		return false
+	}
+	for _, name := range []string{"objects", "refs"} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil || !info.IsDir() {
+			return false
+		}
+	}
+	return true
+}
+
+// sameName compares file names the way the platform's file systems do.
+// Windows ignores case, so a directory listed as .GIT is a git directory.
+func sameName(name, want string) bool {
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(name, want)
+	}
+	return name == want
+}
+
+// longPathMin is the length from which Windows directory paths exceed
+// MAX_PATH once a file name is appended.
+const longPathMin = 248
+
+// longPath makes a deep relative path usable on Windows. The os package
+// lifts the MAX_PATH limit by adding the \\?\ prefix, but only to absolute
+// paths.
+func longPath(path string) string {
+	if runtime.GOOS != "windows" || len(path) < longPathMin || filepath.IsAbs(path) {
+		return path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// gitDirOf returns the .git entry of dir, or dir itself for bare
+// repositories.
+func gitDirOf(dir string) string {
+	gitPath := filepath.Join(dir, ".git")
+	if _, err := os.Lstat(gitPath); err == nil {
+		return gitPath
+	}
+	return dir
+}
+
+// commonDir returns the git directory shared by all worktrees of the
+// repository at dir. Worktrees record it in a commondir file.
+func commonDir(dir string) string {
+	gitPath := gitDirOf(dir)
+	if gitdir, ok := readGitfile(gitPath); ok {
+		gitPath = gitdir
+		if data, err := os.ReadFile(filepath.Join(gitdir, "commondir")); err == nil {
+			common := strings.TrimSpace(string(data))
+			if !filepath.IsAbs(common) {
+				common = filepath.Join(gitdir, common)
+			}
+			gitPath = common
+		}
+	}
+
+	if abs, err := filepath.Abs(gitPath); err == nil {
+		return filepath.Clean(abs)
+	}
+	return filepath.Clean(gitPath)
+}
+
+// lockRepo serializes operations on worktrees that share one git
+// directory, since concurrent fetches would contend for the same ref locks.
+func (g *GitPullCommand) lockRepo(dir string) func() {
+	key := commonDir(dir)
+
+	g.mu.Lock()
+	if g.repoLocks == nil {
+		g.repoLocks = map[string]*sync.Mutex{}
+	}
+	lock, ok := g.repoLocks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		g.repoLocks[key] = lock
+	}
+	g.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSameName(t *testing.T) {
+	windows := runtime.GOOS == "windows"
+	tests := []struct {
+		name, want string
+		same       bool
+	}{
+		{".git", ".git", true},
+		{".GIT", ".git", windows},
+		{"Worktrees", "worktrees", windows},
+		{".git", ".gitmodules", false},
+		{"", ".git", false},
+	}
+	for _, tt := range tests {
+		if got := sameName(tt.name, tt.want); got != tt.same {
+			t.Errorf("sameName(%q, %q) = %v, want %v", tt.name, tt.want, got, tt.same)
+		}
+	}
+}
+
+func TestLongPath(t *testing.T) {
+	deep := strings.Repeat("nested/", longPathMin/len("nested/")+1)
+	abs, err := filepath.Abs(deep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deepWant := deep
+	if runtime.GOOS == "windows" {
+		deepWant = abs
+	}
+
+	tests := []struct {
+		path, want string
+	}{
+		{"short/path", "short/path"},
+		{deep, deepWant},
+		{abs, abs},
+	}
+	for _, tt := range tests {
+		if got := longPath(tt.path); got != tt.want {
+			t.Errorf("longPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestGitfileKind(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name, content, want string
+	}{
+		{"worktree", "gitdir: /src/main/.git/worktrees/feature\n", kindWorktree},
+		{"relative worktree", "gitdir: ../main/.git/worktrees/feature\n", kindWorktree},
+		{"submodule", "gitdir: ../.git/modules/lib\n", kindRepo},
+		{"empty gitdir", "gitdir: \n", kindNone},
+		{"not a gitfile", "[core]\n", kindNone},
+		{"empty", "", kindNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-"))
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if got := gitfileKind(path); got != tt.want {
+				t.Errorf("gitfileKind(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+
+	if got := gitfileKind(filepath.Join(dir, "missing")); got != kindNone {
+		t.Errorf("gitfileKind of a missing file = %q, want %q", got, kindNone)
+	}
+}
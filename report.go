@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	"time"
+)
+
+const reportFormatHTML = "html"
+
+// reportLogLimit caps the commits listed per repository in reports.
+const reportLogLimit = 50
+
+func (g *GitPullCommand) addReportFlags() {
+	g.rootCmd.Flags().StringArrayVar(&g.reports, "report", nil, "Write a report of the run as format:path, e.g. html:report.html (repeatable)")
+}
+
+// parseReport splits a --report value into its format and path.
+func parseReport(value string) (string, string, error) {
+	format, path, ok := strings.Cut(value, ":")
+	if !ok || path == "" {
+		return "", "", fmt.Errorf("invalid --report %q: expected format:path", value)
+	}
+	if format != reportFormatHTML {
+		return "", "", fmt.Errorf("invalid --report format %q (options: %s)", format, reportFormatHTML)
+	}
+	return format, path, nil
+}
+
+func validateReports(values []string) error {
+	for _, value := range values {
+		if _, _, err := parseReport(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordLog keeps the commits a pull brought in for reports.
+func (g *GitPullCommand) recordLog(dir, oldHead, newHead string) {
+	if len(g.reports) == 0 {
+		return
+	}
+
+	log, err := g.client.Log(dir, oldHead, newHead, reportLogLimit)
+	if err != nil {
+		g.logger.Debugf("Could not list new commits in %s: %v", dir, err)
+		return
+	}
+	g.mu.Lock()
+	if r := g.find(dir); r != nil {
+		r.Log = log
+	}
+	g.mu.Unlock()
+}
+
+// writeReports renders every --report once the run has finished.
+func (g *GitPullCommand) writeReports(root string, started time.Time) {
+	for _, value := range g.reports {
+		_, path, err := parseReport(value)
+		if err != nil {
+			continue
+		}
+		if err := g.writeHTMLReport(path, root, started); err != nil {
+			g.logger.Errorf("Error writing report %s: %v", path, err)
+		}
+	}
+}
+
+// htmlReport is the data behind reportTemplate.
+type htmlReport struct {
+	Root     string
+	Started  string
+	Duration string
+	Summary  string
+	Headers  []string
+	Rows     []htmlReportRow
+	Output   string
+	Commits  string
+}
+
+type htmlReportRow struct {
+	Dir         string
+	Remote      string
+	Status      string
+	StatusClass string
+	Duration    string
+	Seconds     float64
+	Commits     int
+	Files       int
+	Log         []string
+	Output      string
+}
+
+func (g *GitPullCommand) writeHTMLReport(path, root string, started time.Time) error {
+	report := htmlReport{
+		Root:     root,
+		Started:  started.Format(time.RFC1123),
+		Duration: formatDuration(time.Since(started)),
+		Summary:  g.summaryLine(g.summary),
+		Headers: []string{
+			g.msg(msgDirectory), g.msg(msgRemote), g.msg(msgStatus),
+			g.msg(msgDuration), g.msg(msgCommits), g.msg(msgFiles),
+		},
+		Output:  g.msg(msgOutput),
+		Commits: g.msg(msgCommits),
+	}
+
+	failed := viewOptions{only: onlyFailed}
+	for _, r := range g.view.apply(g.summary) {
+		class := "ok"
+		switch {
+		case failed.matches(r):
+			class = "failed"
+		case !isSuccess(r.Status):
+			class = "other"
+		}
+		report.Rows = append(report.Rows, htmlReportRow{
+			Dir:         r.Dir,
+			Remote:      displayRemote(r),
+			Status:      g.msg(r.Status),
+			StatusClass: class,
+			Duration:    formatDuration(r.Duration),
+			Seconds:     r.Duration.Seconds(),
+			Commits:     r.Commits,
+			Files:       r.Files,
+			Log:         r.Log,
+			Output:      strings.TrimSpace(r.Output),
+		})
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := reportTemplate.Execute(file, report); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// reportTemplate is a self-contained page: styles and the column sorting
+// script are inline so the file can be mailed or attached as is.
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gitpull: {{.Root}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ddd; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; cursor: pointer; user-select: none; }
+th.asc::after { content: " \25B2"; }
+th.desc::after { content: " \25BC"; }
+td.num { text-align: right; }
+.ok { color: #1a7f37; }
+.failed { color: #cf222e; font-weight: bold; }
+.other { color: #9a6700; }
+pre { margin: 4px 0; white-space: pre-wrap; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>gitpull: {{.Root}}</h1>
+<p>{{.Started}} &middot; {{.Duration}} &middot; {{.Summary}}</p>
+<table>
+<thead><tr>{{range .Headers}}<th>{{.}}</th>{{end}}<th></th></tr></thead>
+<tbody>
+{{- range .Rows}}
+<tr>
+<td>{{.Dir}}</td>
+<td>{{.Remote}}</td>
+<td class="{{.StatusClass}}">{{.Status}}</td>
+<td class="num" data-sort="{{.Seconds}}">{{.Duration}}</td>
+<td class="num">{{.Commits}}</td>
+<td class="num">{{.Files}}</td>
+<td>
+{{- if .Log}}<details><summary>{{$.Commits}}</summary><pre>{{range .Log}}{{.}}
+{{end}}</pre></details>{{end}}
+{{- if .Output}}<details{{if eq .StatusClass "failed"}} open{{end}}><summary>{{$.Output}}</summary><pre>{{.Output}}</pre></details>{{end -}}
+</td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+<script>
+document.querySelectorAll("th").forEach(function (th, col) {
+  th.addEventListener("click", function () {
+    var tbody = th.closest("table").tBodies[0];
+    var asc = !th.classList.contains("asc");
+    th.parentNode.querySelectorAll("th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+    th.classList.add(asc ? "asc" : "desc");
+    var key = function (row) {
+      var cell = row.cells[col];
+      var value = cell.dataset.sort !== undefined ? cell.dataset.sort : cell.textContent.trim();
+      return cell.classList.contains("num") ? parseFloat(value) || 0 : value.toLowerCase();
+    };
+    Array.from(tbody.rows)
+      .sort(function (a, b) { var x = key(a), y = key(b); return (x < y ? -1 : x > y ? 1 : 0) * (asc ? 1 : -1); })
+      .forEach(function (row) { tbody.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// gitRunner executes git subcommands inside a repository. The default
+// implementation shells out to git; simulations substitute a fake.
+type gitRunner interface {
+	Run(dir string, args ...string) ([]byte, error)
+}
+
+// gitWaitDelay is how long an interrupted git process gets to clean up its
+// lock files before it is killed.
+const gitWaitDelay = 10 * time.Second
+
+// execRunner runs the git binary at git, by default the one found in PATH,
+// with env added to the inherited environment, followed by the settings for
+// the repository. Cancelling ctx interrupts running commands. With live set,
+// their output is also streamed as it arrives.
+type execRunner struct {
+	ctx      context.Context
+	git      string
+	env      []string
+	settings *repoSettings
+	live     *liveOutput
+}
+
+// findGit locates the git binary. Git for Windows does not always put
+// git.exe on PATH, so its default install locations are tried as well.
+func findGit() (string, error) {
+	path, err := exec.LookPath("git")
+	if err == nil || runtime.GOOS != "windows" {
+		return path, err
+	}
+
+	for _, base := range []string{os.Getenv("ProgramFiles"), os.Getenv("ProgramW6432"), filepath.Join(os.Getenv("LOCALAPPDATA"), "Programs")} {
+		if base == "" {
+			continue
+		}
+		candidate := filepath.Join(base, "Git", "cmd", "git.exe")
+		if _, statErr := os.Stat(candidate); statErr == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("git not found in PATH or the Git for Windows install locations: %v", err)
+}
+
+// requireGit fails when the options or commands named by what run the git
+// binary and it was not found, which only the go-git backend tolerates.
+func (g *GitPullCommand) requireGit(what ...string) error {
+	if g.noGit == nil || len(what) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s cannot be used with the %s backend without the git binary: %v", strings.Join(what, ", "), backendGoGit, g.noGit)
+}
+
+// binaryOptions returns the enabled options whose features run the git
+// binary rather than the selected backend.
+func (g *GitPullCommand) binaryOptions() []string {
+	var names []string
+	add := func(enabled bool, name string) {
+		if enabled {
+			names = append(names, name)
+		}
+	}
+
+	pins, checkoutDefault := false, g.branches.checkoutDefault
+	for _, o := range g.settings.perPath {
+		pins = pins || o.Pin != ""
+		checkoutDefault = checkoutDefault || (o.CheckoutDefault != nil && *o.CheckoutDefault)
+	}
+
+	add(checkoutDefault, "--checkout-default")
+	add(pins, "pins in the config file")
+	add(len(g.remote.rewrite) > 0, "--rewrite-remote")
+	add(g.remote.check, "--check-remote")
+	add(g.prune.remote, "--prune")
+	add(g.lfs, "--lfs")
+	add(g.popStash, "--pop-stash")
+	add(g.view.wantsDetails(), "--columns "+strings.Join(g.view.columns, ","))
+	add(g.safeDirectory.mode != "", "--add-safe-directory")
+	add(g.verboseGit, "--verbose-git")
+	return names
+}
+
+func (r execRunner) Run(dir string, args ...string) ([]byte, error) {
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var env, gitConfig []string
+	if r.settings != nil {
+		env, gitConfig = r.settings.forDir(dir)
+	}
+
+	gitArgs := []string{"-C", dir}
+	if runtime.GOOS == "windows" {
+		// Working trees nested deeper than MAX_PATH are common on Windows.
+		gitArgs = append(gitArgs, "-c", "core.longpaths=true")
+	}
+	for _, kv := range gitConfig {
+		gitArgs = append(gitArgs, "-c", kv)
+	}
+
+	git := r.git
+	if git == "" {
+		git = "git"
+	}
+	cmd := exec.CommandContext(ctx, git, append(gitArgs, args...)...)
+	cmd.Env = append(append(os.Environ(), r.env...), env...)
+	cmd.Cancel = func() error {
+		// Git removes its lock files on SIGINT but not when killed.
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = gitWaitDelay
+	if r.live == nil {
+		return cmd.CombinedOutput()
+	}
+
+	r.live.command(dir, args)
+	var output bytes.Buffer
+	live := r.live.writer(dir)
+	cmd.Stdout = io.MultiWriter(&output, live)
+	cmd.Stderr = cmd.Stdout
+	err := cmd.Run()
+	live.flush()
+	return output.Bytes(), err
+}
+
+func (r execRunner) withContext(ctx context.Context) gitRunner {
+	r.ctx = ctx
+	return r
+}
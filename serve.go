@@ -0,0 +1,462 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// envServeToken holds the token clients of the serve subcommand must send,
+// kept out of the command line so it does not show up in ps.
+const envServeToken = "GITPULL_SERVE_TOKEN"
+
+// serveRefresh is how often the event stream checks for status changes.
+const serveRefresh = 500 * time.Millisecond
+
+// serveOptions are the flags of the serve subcommand.
+type serveOptions struct {
+	listen   string
+	root     string
+	schedule string
+	jitter   time.Duration
+}
+
+// server triggers pulls of the repositories below root over HTTP. One run
+// happens at a time; the results stay in g.summary between runs like in the
+// shell.
+type server struct {
+	g             *GitPullCommand
+	root          string
+	token         string
+	webhookSecret string
+
+	mu       sync.Mutex
+	running  bool
+	started  time.Time
+	finished time.Time
+	nextRun  time.Time
+	// queued holds the repositories pushed to during a run.
+	queued []*repoResult
+}
+
+// serveResult is the JSON form of a repository's latest result.
+type serveResult struct {
+	Dir      string  `json:"dir"`
+	Remote   string  `json:"remote"`
+	Status   string  `json:"status"`
+	Duration float64 `json:"duration_seconds"`
+	Commits  int     `json:"commits"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// serveStatus is the response of GET /status.
+type serveStatus struct {
+	Running  bool          `json:"running"`
+	Started  *time.Time    `json:"started,omitempty"`
+	Finished *time.Time    `json:"finished,omitempty"`
+	NextRun  *time.Time    `json:"next_run,omitempty"`
+	Summary  string        `json:"summary"`
+	Results  []serveResult `json:"results"`
+}
+
+func (g *GitPullCommand) newServeCommand() *cobra.Command {
+	opts := serveOptions{listen: ":8080"}
+
+	cmd := &cobra.Command{
+		Use:   "serve --root <dir>",
+		Short: "Serve an HTTP API to trigger pulls and follow their progress",
+		Long: `Serve an HTTP API for the repositories below --root:
+
+  POST /pull     pull all repositories, or those matching ?repo=<glob> (repeatable)
+  GET  /status   the current or last run as JSON
+  GET  /events   server-sent events for every status change
+  GET  /metrics  Prometheus metrics
+  POST /webhook  GitHub or GitLab push event; pulls the clones of the pushed repository
+
+With --schedule, every repository is also pulled on a cron schedule, e.g.
+"0 */4 * * *", each run delayed by a random part of --jitter.
+
+When ` + envServeToken + ` is set, every request must send it as
+"Authorization: Bearer <token>". When ` + envWebhookSecret + ` is set,
+/webhook checks the GitHub signature or GitLab token against it instead.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			stopSignals := g.handleSignals()
+			defer stopSignals()
+
+			if err := g.setupClient(); err != nil {
+				g.logger.Errorf("Error: %v", err)
+				os.Exit(1)
+			}
+
+			if err := validateOnConflict(g.onConflict); err != nil {
+				g.logger.Errorf("Error: %v", err)
+				os.Exit(1)
+			}
+
+			var schedule *cronSchedule
+			if opts.schedule != "" {
+				var err error
+				if schedule, err = parseSchedule(opts.schedule); err != nil {
+					g.logger.Errorf("Error: %v", err)
+					os.Exit(1)
+				}
+			}
+			if opts.jitter < 0 || (opts.jitter > 0 && schedule == nil) {
+				g.logger.Errorf("Error: --jitter must be positive and requires --schedule")
+				os.Exit(1)
+			}
+
+			s := &server{g: g, root: opts.root, token: os.Getenv(envServeToken), webhookSecret: os.Getenv(envWebhookSecret)}
+			if schedule != nil {
+				go s.runSchedule(schedule, opts.jitter)
+			}
+			if err := s.listenAndServe(opts.listen); err != nil {
+				g.logger.Errorf("Error: %v", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.listen, "listen", opts.listen, "Address to serve the API on")
+	cmd.Flags().StringVar(&opts.root, "root", "", "Directory whose repositories the API pulls")
+	cmd.Flags().StringVar(&opts.schedule, "schedule", "", "Also pull every repository on this cron schedule, e.g. \"0 */4 * * *\" or @daily")
+	cmd.Flags().DurationVar(&opts.jitter, "jitter", 0, "Delay each scheduled run by a random duration up to this, e.g. 5m")
+	cmd.MarkFlagRequired("root")
+
+	return cmd
+}
+
+// listenAndServe discovers the repositories and serves the API until the
+// process is interrupted.
+func (s *server) listenAndServe(addr string) error {
+	g := s.g
+	if s.token == "" && !isLoopback(addr) {
+		g.logger.Warnf("Serving on %s without %s: anyone who can reach it can trigger pulls", addr, envServeToken)
+	}
+
+	g.rescan(s.root, g.discover)
+	g.logger.Infof("Serving %d repositories below %s on %s", len(g.summary), s.root, addr)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pull", s.handlePull)
+	mux.HandleFunc("/status", s.handleStatus)
+	mux.HandleFunc("/events", s.handleEvents)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/webhook", s.handleWebhook)
+
+	srv := &http.Server{Addr: addr, Handler: s.authorize(mux)}
+	go func() {
+		<-g.ctx.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	err := srv.ListenAndServe()
+	g.wait()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// authorize rejects requests without the bearer token when one is set.
+// Forges cannot send one, so the webhook checks its own secret.
+func (s *server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/webhook" && !s.authorized(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *server) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return true
+	}
+	got := []byte(r.Header.Get("Authorization"))
+	return subtle.ConstantTimeCompare(got, []byte("Bearer "+s.token)) == 1
+}
+
+// handlePull starts a run in the background and answers 202 right away, or
+// 409 while another run is still going.
+func (s *server) handlePull(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	selected, err := s.selectRepos(r.URL.Query()["repo"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(selected) == 0 {
+		http.Error(w, "no repositories match", http.StatusNotFound)
+		return
+	}
+
+	status, err := s.startRun(selected, false)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	s.writeJSON(w, status, s.status())
+}
+
+// selectRepos returns the repositories matching any of patterns, or all of
+// them, including any cloned since the last run, when there are none.
+func (s *server) selectRepos(patterns []string) ([]*repoResult, error) {
+	g := s.g
+	if len(patterns) == 0 {
+		g.rescan(s.root, g.discover)
+		patterns = []string{""}
+	}
+
+	var selected []*repoResult
+	for _, pattern := range patterns {
+		results, err := g.matching(s.root, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		selected = append(selected, results...)
+	}
+	return uniqueResults(selected), nil
+}
+
+func uniqueResults(results []*repoResult) []*repoResult {
+	seen := map[string]bool{}
+	var unique []*repoResult
+	for _, r := range results {
+		if !seen[r.Dir] {
+			seen[r.Dir] = true
+			unique = append(unique, r)
+		}
+	}
+	return unique
+}
+
+// startRun pulls selected in the background and returns the HTTP status to
+// answer with. While another run is in progress it fails with 409 or, with
+// queue, keeps the repositories for a follow-up run.
+func (s *server) startRun(selected []*repoResult, queue bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		if !queue {
+			return http.StatusConflict, fmt.Errorf("a run started at %s is still in progress", s.started.Format(time.RFC3339))
+		}
+		s.queued = append(s.queued, selected...)
+		s.g.logger.Infof("Queued %d repositories behind the current run", len(selected))
+		return http.StatusAccepted, nil
+	}
+	return s.launch(selected)
+}
+
+// launch starts a run of selected. The caller must hold s.mu.
+func (s *server) launch(selected []*repoResult) (int, error) {
+	g := s.g
+	lock, err := g.lockRun(cacheKey(s.root))
+	if err != nil {
+		return http.StatusConflict, err
+	}
+
+	g.loadQuarantine()
+	g.mu.Lock()
+	for _, r := range selected {
+		g.updateStatus(r.Dir, statusQueued)
+		g.updateOutput(r.Dir, "")
+		g.updateCommits(r.Dir, 0)
+		r.Duration = 0
+	}
+	g.mu.Unlock()
+
+	s.running = true
+	s.started = time.Now()
+	g.logger.Infof("Pulling %d repositories", len(selected))
+	go s.run(selected, lock)
+	return http.StatusAccepted, nil
+}
+
+func (s *server) run(selected []*repoResult, lock *os.File) {
+	g := s.g
+	for _, r := range selected {
+		g.spawn(r.Dir, g.pullRepository)
+	}
+	g.wait()
+	g.finishRun()
+	if lock != nil {
+		lock.Close()
+	}
+
+	s.mu.Lock()
+	started := s.started
+	s.mu.Unlock()
+	if !g.noHistory && g.historyDir != "" {
+		g.recordRun(s.root, started)
+	}
+	g.saveMetricsFile()
+
+	g.mu.Lock()
+	g.logger.Infof("Run finished: %s", g.summaryLine(selected))
+	g.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = false
+	s.finished = time.Now()
+	if queued := uniqueResults(s.queued); len(queued) > 0 && g.ctx.Err() == nil {
+		s.queued = nil
+		if _, err := s.launch(queued); err != nil {
+			g.logger.Errorf("Error starting the queued run: %v", err)
+		}
+	}
+}
+
+func (s *server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, s.status())
+}
+
+// status snapshots the current run and the latest result of every
+// repository.
+func (s *server) status() serveStatus {
+	g := s.g
+
+	s.mu.Lock()
+	st := serveStatus{Running: s.running}
+	if !s.started.IsZero() {
+		started := s.started
+		st.Started = &started
+	}
+	if !s.running && !s.finished.IsZero() {
+		finished := s.finished
+		st.Finished = &finished
+	}
+	if !s.nextRun.IsZero() {
+		next := s.nextRun
+		st.NextRun = &next
+	}
+	s.mu.Unlock()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var pulled []*repoResult
+	st.Results = make([]serveResult, 0, len(g.summary))
+	for _, r := range g.summary {
+		st.Results = append(st.Results, newServeResult(r))
+		if r.Status != statusPending {
+			pulled = append(pulled, r)
+		}
+	}
+	// Repositories never pulled yet are not counted as up-to-date.
+	st.Summary = g.summaryLine(pulled)
+	return st
+}
+
+func newServeResult(r *repoResult) serveResult {
+	result := serveResult{Dir: r.Dir, Remote: r.Remote, Status: r.Status, Duration: r.Duration.Seconds(), Commits: r.Commits}
+	if (viewOptions{only: onlyFailed}).matches(r) {
+		result.Error = r.Output
+	}
+	return result
+}
+
+// handleEvents streams a "repo" event whenever a repository changes status
+// and a "run" event when a run starts or finishes, checking every
+// serveRefresh like the TUI does.
+func (s *server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	last := map[string]string{}
+	running := false
+	first := true
+	ticker := time.NewTicker(serveRefresh)
+	defer ticker.Stop()
+
+	for {
+		st := s.status()
+		if first || st.Running != running {
+			running = st.Running
+			writeEvent(w, "run", map[string]interface{}{"running": st.Running, "summary": st.Summary})
+		}
+		for _, result := range st.Results {
+			if status, ok := last[result.Dir]; !ok || status != result.Status {
+				last[result.Dir] = result.Status
+				if !first {
+					writeEvent(w, "repo", result)
+				}
+			}
+		}
+		first = false
+		flusher.Flush()
+
+		select {
+		case <-ticker.C:
+		case <-r.Context().Done():
+			return
+		case <-s.g.ctx.Done():
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
+
+func (s *server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	s.g.mu.Lock()
+	s.g.writeMetrics(&buf)
+	s.g.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(buf.Bytes())
+}
+
+func (s *server) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// repoSettings are environment variables and git config options added to
+// every git invocation, globally from flags and per path from the config
+// file, e.g.
+//
+//	repos:
+//	  - path: ~/work/corp
+//	    env:
+//	      GIT_SSH_COMMAND: ssh -i ~/.ssh/corp
+//	    git-config:
+//	      http.proxy: http://proxy.corp:3128
+type repoSettings struct {
+	env       []string
+	gitConfig []string
+	perPath   []repoOverride
+
+	// safe holds the safe.directory paths of repositories trusted for this
+	// run with --add-safe-directory run, by directory.
+	mu   sync.Mutex
+	safe map[string]string
+}
+
+// repoOverride applies to the repositories at or below Path, which may also
+// be a glob pattern.
+type repoOverride struct {
+	Path      string            `yaml:"path"`
+	Env       map[string]string `yaml:"env"`
+	GitConfig map[string]string `yaml:"git-config"`
+
+	ProtectBranches []string `yaml:"protect-branches"`
+	CheckoutDefault *bool    `yaml:"checkout-default"`
+
+	// Pin is a tag, branch or commit checked out instead of pulling.
+	Pin string `yaml:"pin"`
+}
+
+func (g *GitPullCommand) addSettingsFlags() {
+	flags := g.rootCmd.PersistentFlags()
+	flags.StringArrayVar(&g.settings.env, "env", nil, "Environment variable KEY=VALUE set for git commands (repeatable)")
+	flags.StringArrayVarP(&g.settings.gitConfig, "git-config", "c", nil, "Git config option key=value passed to git commands with -c (repeatable)")
+}
+
+func (s *repoSettings) validate(backend string) error {
+	for _, kv := range append(append([]string{}, s.env...), s.gitConfig...) {
+		if !strings.Contains(kv, "=") {
+			return fmt.Errorf("invalid setting %q: expected key=value", kv)
+		}
+	}
+	perPath := false
+	for _, o := range s.perPath {
+		perPath = perPath || len(o.Env) > 0 || len(o.GitConfig) > 0
+	}
+	if backend != backendExec && (len(s.env) > 0 || len(s.gitConfig) > 0 || perPath) {
+		return fmt.Errorf("--env, --git-config and per-path settings require the %s backend", backendExec)
+	}
+	return nil
+}
+
+// forDir returns the environment and git config for a repository. Later
+// entries override earlier ones, so per-path settings win over flags.
+func (s *repoSettings) forDir(dir string) (env, gitConfig []string) {
+	env = append(env, s.env...)
+	gitConfig = append(gitConfig, s.gitConfig...)
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = dir
+	}
+	for _, o := range s.perPath {
+		if o.matches(abs) {
+			env = append(env, sortedPairs(o.Env)...)
+			gitConfig = append(gitConfig, sortedPairs(o.GitConfig)...)
+		}
+	}
+
+	s.mu.Lock()
+	if path, ok := s.safe[dir]; ok {
+		gitConfig = append(gitConfig, "safe.directory="+path)
+	}
+	s.mu.Unlock()
+	return env, gitConfig
+}
+
+// trust passes safe.directory=path to the git commands run in dir.
+func (s *repoSettings) trust(dir, path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.safe == nil {
+		s.safe = map[string]string{}
+	}
+	s.safe[dir] = path
+}
+
+func (o repoOverride) matches(dir string) bool {
+	path := expandHome(o.Path)
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	if dir == path || strings.HasPrefix(dir, path+string(filepath.Separator)) {
+		return true
+	}
+	ok, _ := filepath.Match(path, dir)
+	return ok
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// sortedPairs renders a map as key=value pairs in a stable order.
+func sortedPairs(m map[string]string) []string {
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
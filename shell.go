@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const shellHelp = `Commands:
+  pull [pattern]  pull all repositories, or those whose path matches the glob pattern
+  status          show the last known status of every repository
+  failed          list repositories whose last pull failed
+  retry           pull the failed repositories again
+  rescan          walk the directory again to pick up new repositories
+  help            show this help
+  quit            leave the shell
+`
+
+func (g *GitPullCommand) newShellCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "shell <dir>",
+		Short: "Interactive loop that keeps discovered repositories in memory",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := g.setupClient(); err != nil {
+				g.logger.Errorf("Error: %v", err)
+				os.Exit(1)
+			}
+
+			if err := validateOnConflict(g.onConflict); err != nil {
+				g.logger.Errorf("Error: %v", err)
+				os.Exit(1)
+			}
+
+			g.serveMetrics()
+			g.runShell(args[0], cmd.InOrStdin(), cmd.OutOrStdout())
+		},
+	}
+}
+
+// runShell discovers repositories once and then executes commands read
+// from in until quit or end of input.
+func (g *GitPullCommand) runShell(dir string, in io.Reader, out io.Writer) {
+	g.rescan(dir, g.discover)
+	fmt.Fprintf(out, "Found %d repositories. Type 'help' for commands.\n", len(g.summary))
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "gitpull> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return
+		}
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "pull":
+			pattern := ""
+			if len(fields) > 1 {
+				pattern = fields[1]
+			}
+			results, err := g.matching(dir, pattern)
+			if err != nil {
+				fmt.Fprintf(out, "Invalid pattern: %v\n", err)
+				continue
+			}
+			if len(results) == 0 {
+				fmt.Fprintln(out, "No repositories match.")
+				continue
+			}
+			for _, r := range results {
+				g.mu.Lock()
+				g.updateStatus(r.Dir, statusQueued)
+				g.mu.Unlock()
+				g.spawn(r.Dir, g.pullRepository)
+			}
+			g.wait()
+			g.finishRun()
+			g.printResults(results)
+		case "status":
+			g.printSummary()
+		case "failed":
+			for _, r := range g.withStatus(statusFailed) {
+				fmt.Fprintln(out, r.Dir)
+			}
+		case "retry":
+			failed := g.withStatus(statusFailed)
+			if len(failed) == 0 {
+				fmt.Fprintln(out, "No failed repositories.")
+				continue
+			}
+			for _, r := range failed {
+				g.retry(r.Dir)
+			}
+			g.wait()
+			g.finishRun()
+			g.printResults(failed)
+		case "rescan":
+			g.rescan(dir, g.walk)
+			fmt.Fprintf(out, "Found %d repositories.\n", len(g.summary))
+		case "help":
+			fmt.Fprint(out, shellHelp)
+		case "quit", "exit":
+			return
+		default:
+			fmt.Fprintf(out, "Unknown command %q. Type 'help' for commands.\n", fields[0])
+		}
+	}
+}
+
+// rescan discovers repositories below dir with discover and adds those
+// not seen before, keeping the results of those already known.
+func (g *GitPullCommand) rescan(dir string, discover func(string, func(string))) {
+	discover(dir, func(repoDir string) {
+		g.addResult(repoDir, statusPending)
+	})
+}
+
+// matching returns the repositories whose path relative to root, or whose
+// base name, matches the glob pattern. An empty pattern matches everything.
+func (g *GitPullCommand) matching(root, pattern string) ([]*repoResult, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var results []*repoResult
+	for _, r := range g.summary {
+		if pattern == "" {
+			results = append(results, r)
+			continue
+		}
+		if matchesPath(root, r.Dir, pattern) {
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
+
+// matchesPath reports whether the glob pattern matches dir relative to
+// root or the base name of dir.
+func matchesPath(root, dir, pattern string) bool {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		rel = dir
+	}
+	if ok, _ := filepath.Match(pattern, rel); ok {
+		return true
+	}
+	ok, _ := filepath.Match(pattern, filepath.Base(dir))
+	return ok
+}
+
+func (g *GitPullCommand) withStatus(status string) []*repoResult {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var results []*repoResult
+	for _, r := range g.summary {
+		if r.Status == status {
+			results = append(results, r)
+		}
+	}
+	return results
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// simOptions configures the hidden simulation mode used to exercise the
+// scheduler, views and reporting without real clones.
+type simOptions struct {
+	repos      int
+	latency    time.Duration
+	jitter     time.Duration
+	failRate   float64
+	updateRate float64
+	seed       int64
+}
+
+func (g *GitPullCommand) addSimulationFlags() {
+	flags := g.rootCmd.Flags()
+	flags.IntVar(&g.sim.repos, "simulate", 0, "Pull N fabricated repositories instead of a real directory")
+	flags.DurationVar(&g.sim.latency, "sim-latency", 200*time.Millisecond, "Average duration of a simulated pull")
+	flags.DurationVar(&g.sim.jitter, "sim-jitter", 100*time.Millisecond, "Maximum random deviation from --sim-latency")
+	flags.Float64Var(&g.sim.failRate, "sim-fail-rate", 0.1, "Fraction of simulated pulls that fail (0-1)")
+	flags.Float64Var(&g.sim.updateRate, "sim-update-rate", 0.3, "Fraction of simulated pulls that bring in new commits (0-1)")
+	flags.Int64Var(&g.sim.seed, "sim-seed", 0, "Random seed for simulations (0 picks one from the clock)")
+
+	for _, name := range []string{"simulate", "sim-latency", "sim-jitter", "sim-fail-rate", "sim-update-rate", "sim-seed"} {
+		flags.MarkHidden(name)
+	}
+}
+
+// startSimulation writes a fixture tree to a temporary directory and swaps in
+// a fake runner. The returned cleanup removes the fixture.
+func (g *GitPullCommand) startSimulation() (string, func(), error) {
+	if g.sim.failRate < 0 || g.sim.failRate > 1 {
+		return "", nil, errors.New("--sim-fail-rate must be between 0 and 1")
+	}
+	if g.sim.updateRate < 0 || g.sim.updateRate > 1 {
+		return "", nil, errors.New("--sim-update-rate must be between 0 and 1")
+	}
+
+	root, err := os.MkdirTemp("", "gitpull-sim-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(root) }
+
+	if err := writeFixture(root, g.sim.repos); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	g.runner = newSimRunner(g.sim)
+	g.logger.Infof("Simulating %d repositories in %s", g.sim.repos, root)
+	return root, cleanup, nil
+}
+
+func (g *GitPullCommand) newFixtureCommand() *cobra.Command {
+	var count int
+
+	cmd := &cobra.Command{
+		Use:    "fixture <dir>",
+		Short:  "Generate a tree of fake repositories for simulations and benchmarks",
+		Hidden: true,
+		Args:   cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return writeFixture(args[0], count)
+		},
+	}
+	cmd.Flags().IntVarP(&count, "count", "n", 100, "Number of repositories to generate")
+
+	return cmd
+}
+
+// fixtureGroupSize is the number of fake repositories per group directory,
+// keeping the generated tree a few levels deep like a real workspace.
+const fixtureGroupSize = 50
+
+// writeFixture creates n directories below root that look like repositories
+// to the walker. Only the .git marker is created; git itself is never run
+// against them.
+func writeFixture(root string, n int) error {
+	for i := 0; i < n; i++ {
+		dir := filepath.Join(root,
+			fmt.Sprintf("group-%03d", i/fixtureGroupSize),
+			fmt.Sprintf("repo-%05d", i),
+			".git")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// simRunner answers git invocations with canned output after a random delay.
+// Each fake repository's HEAD is a commit counter that merges advance.
+type simRunner struct {
+	opts  simOptions
+	mu    sync.Mutex
+	rand  *rand.Rand
+	heads map[string]int
+}
+
+func newSimRunner(opts simOptions) *simRunner {
+	seed := opts.seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &simRunner{opts: opts, rand: rand.New(rand.NewSource(seed)), heads: map[string]int{}}
+}
+
+func (s *simRunner) Run(dir string, args ...string) ([]byte, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	name := filepath.Base(dir)
+	switch args[0] {
+	case "remote":
+		url := fmt.Sprintf("https://git.example.invalid/sim/%s.git", name)
+		return []byte(fmt.Sprintf("origin\t%s (fetch)\norigin\t%s (push)\n", url, url)), nil
+	case "status":
+		return []byte("## main...origin/main\n"), nil
+	case "rev-parse":
+		if args[len(args)-1] == "HEAD" {
+			return []byte(s.head(dir) + "\n"), nil
+		}
+		return []byte("origin/main\n"), nil
+	case "rev-list":
+		var from, to int
+		if _, err := fmt.Sscanf(args[len(args)-1], "%x..%x", &from, &to); err == nil {
+			return []byte(fmt.Sprintf("%d\n", to-from)), nil
+		}
+		return []byte(fmt.Sprintf("%d\n", s.intn(4))), nil
+	case "diff":
+		var from, to int
+		if _, err := fmt.Sscanf(args[len(args)-2]+" "+args[len(args)-1], "%x %x", &from, &to); err == nil {
+			return []byte(strings.Repeat("file\x00", 2*(to-from))), nil
+		}
+		return nil, nil
+	case "merge", "rebase":
+		if n := s.advance(dir); n > 0 {
+			return []byte(fmt.Sprintf("Fast-forward\n %d files changed\n", n)), nil
+		}
+		return []byte("Already up to date.\n"), nil
+	case "symbolic-ref":
+		return []byte("main\n"), nil
+	case "config":
+		return nil, errors.New("exit status 1")
+	case "merge-base", "merge-tree":
+		return nil, nil
+	case "fetch", "pull", "push":
+		delay, fail := s.roll()
+		time.Sleep(delay)
+		if fail {
+			return []byte("fatal: simulated failure\n"), errors.New("exit status 1")
+		}
+		switch args[0] {
+		case "fetch":
+			return nil, nil
+		case "push":
+			return []byte("To https://git.example.invalid/sim/" + name + ".git\n"), nil
+		}
+		return []byte("Already up to date.\n"), nil
+	}
+
+	return nil, nil
+}
+
+// roll draws the delay and outcome for one simulated network operation.
+func (s *simRunner) roll() (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delay := s.opts.latency
+	if s.opts.jitter > 0 {
+		delay += time.Duration(s.rand.Int63n(int64(2*s.opts.jitter))) - s.opts.jitter
+	}
+	if delay < 0 {
+		delay = 0
+	}
+
+	return delay, s.rand.Float64() < s.opts.failRate
+}
+
+func (s *simRunner) intn(n int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rand.Intn(n)
+}
+
+// head returns the fake commit id at HEAD of dir.
+func (s *simRunner) head(dir string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fmt.Sprintf("%040x", s.heads[dir])
+}
+
+// advance moves HEAD of dir forward by a few commits with probability
+// updateRate and returns how many.
+func (s *simRunner) advance(dir string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.rand.Float64() >= s.opts.updateRate {
+		return 0
+	}
+	n := 1 + s.rand.Intn(5)
+	s.heads[dir] += n
+	return n
+}
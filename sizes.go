@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// largestRepos is how many repositories the largest repositories section
+// lists.
+const largestRepos = 10
+
+func (g *GitPullCommand) addSizesFlags() {
+	g.rootCmd.Flags().BoolVar(&g.sizes, "sizes", false, "Measure the git directory and working tree of every repository and list the largest ones")
+}
+
+// measureSizes records the disk usage of dir. Linked worktrees report the
+// git directory they share with their main worktree.
+func (g *GitPullCommand) measureSizes(dir string) {
+	gitDir := commonDir(dir)
+	gitSize := dirSize(gitDir)
+
+	var treeSize int64
+	if detectRepo(dir) != kindBare {
+		treeSize = workTreeSize(dir)
+	}
+
+	g.mu.Lock()
+	if r := g.find(dir); r != nil {
+		r.GitSize = gitSize
+		r.TreeSize = treeSize
+	}
+	g.mu.Unlock()
+}
+
+// workTreeSize sums the files of the working tree at dir, leaving out git
+// directories and nested repositories, which are measured on their own.
+func workTreeSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if sameName(info.Name(), ".git") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			if path != dir && detectRepo(path) != kindNone {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		size += info.Size()
+		return nil
+	})
+	return size
+}
+
+func (r *repoResult) totalSize() int64 {
+	return r.GitSize + r.TreeSize
+}
+
+// printLargest lists the repositories using the most disk space.
+func (g *GitPullCommand) printLargest(results []*repoResult) {
+	largest := append([]*repoResult{}, results...)
+	sort.SliceStable(largest, func(i, j int) bool { return largest[i].totalSize() > largest[j].totalSize() })
+	if len(largest) > largestRepos {
+		largest = largest[:largestRepos]
+	}
+
+	var total int64
+	for _, r := range results {
+		total += r.totalSize()
+	}
+
+	rows := make([][]string, 0, len(largest))
+	for _, r := range largest {
+		rows = append(rows, []string{r.Dir, formatBytes(r.GitSize), formatBytes(r.TreeSize), formatBytes(r.totalSize())})
+	}
+
+	fmt.Printf("\n%s (%s: %s)\n", g.msg(msgLargest), g.msg(msgTotal), formatBytes(total))
+	g.renderTable([]string{g.msg(msgDirectory), g.msg(msgGitSize), g.msg(msgTreeSize), g.msg(msgTotal)}, rows)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// stashEntry is one stash of a repository.
+type stashEntry struct {
+	ref     string
+	age     string
+	message string
+}
+
+func (g *GitPullCommand) addStashFlags() {
+	g.rootCmd.PersistentFlags().BoolVar(&g.popStash, "pop-stash", false, "After a successful pull, apply and drop the latest stash of repositories with a clean working tree, keeping it when it does not apply cleanly")
+}
+
+func (g *GitPullCommand) newStashesCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stashes <dir>",
+		Short: "List the stashes of every repository",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			stopSignals := g.handleSignals()
+			defer stopSignals()
+
+			if err := g.setupClient(); err != nil {
+				g.logger.Errorf("Error: %v", err)
+				os.Exit(1)
+			}
+			if err := g.requireGit("gitpull stashes"); err != nil {
+				g.logger.Errorf("Error: %v", err)
+				os.Exit(1)
+			}
+
+			stashes := map[string][]stashEntry{}
+			g.forEachRepo(args[0], func(dir string) {
+				entries := g.listStashes(dir)
+				g.mu.Lock()
+				stashes[dir] = entries
+				g.mu.Unlock()
+			})
+			g.printStashes(stashes)
+		},
+	}
+}
+
+// listStashes returns the stashes of dir, newest first.
+func (g *GitPullCommand) listStashes(dir string) []stashEntry {
+	output, err := g.runner.Run(dir, "stash", "list", "--format=%gd%x00%cr%x00%gs")
+	if err != nil {
+		g.logger.Debugf("Could not list stashes in %s: %v", dir, err)
+		return nil
+	}
+
+	var entries []stashEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.SplitN(line, "\x00", 3)
+		if len(fields) == 3 {
+			entries = append(entries, stashEntry{ref: fields[0], age: fields[1], message: fields[2]})
+		}
+	}
+	return entries
+}
+
+// countStashes returns how many stashes dir has.
+func (g *GitPullCommand) countStashes(dir string) int {
+	output, err := g.runner.Run(dir, "rev-list", "--walk-reflogs", "--count", "refs/stash")
+	if err != nil {
+		// Without any stash, refs/stash does not exist.
+		return 0
+	}
+	count, _ := strconv.Atoi(strings.TrimSpace(string(output)))
+	return count
+}
+
+// popLatestStash applies the latest stash of dir after a pull and drops it
+// once it applied cleanly. The working tree must be clean beforehand, so a
+// stash that conflicts can be undone by a hard reset without losing work;
+// the stash itself is kept in that case.
+func (g *GitPullCommand) popLatestStash(dir string) []byte {
+	if g.countStashes(dir) == 0 {
+		return nil
+	}
+
+	status, err := g.runner.Run(dir, "status", "--porcelain", "--untracked-files=no")
+	if err != nil || strings.TrimSpace(string(status)) != "" {
+		g.logger.Warnf("Keeping the stash of %s: the working tree has changes", dir)
+		return []byte("Kept the latest stash: the working tree has changes\n")
+	}
+
+	output, err := g.runner.Run(dir, "stash", "apply")
+	if err != nil {
+		g.logger.Warnf("Keeping the stash of %s: it does not apply cleanly", dir)
+		if resetOutput, err := g.runner.Run(dir, "reset", "--hard", "--quiet", "HEAD"); err != nil {
+			g.logger.Errorf("Error undoing the stash in %s: %v", dir, err)
+			output = append(output, resetOutput...)
+		}
+		return append(output, "Kept the latest stash: it does not apply cleanly\n"...)
+	}
+
+	if dropOutput, err := g.runner.Run(dir, "stash", "drop"); err != nil {
+		g.logger.Errorf("Error dropping the applied stash in %s: %v", dir, err)
+		output = append(output, dropOutput...)
+	}
+	g.logger.Infof("Popped the latest stash in %s", dir)
+	return output
+}
+
+func (g *GitPullCommand) printStashes(stashes map[string][]stashEntry) {
+	var rows [][]string
+	total, repos := 0, 0
+	for _, r := range g.summary {
+		entries := stashes[r.Dir]
+		if len(entries) > 0 {
+			repos++
+		}
+		for _, e := range entries {
+			rows = append(rows, []string{r.Dir, e.ref, e.age, e.message})
+			total++
+		}
+	}
+
+	if total == 0 {
+		fmt.Println(g.msg(msgNoStashes))
+		return
+	}
+	g.renderTable([]string{g.msg(msgDirectory), g.msg(msgStash), g.msg(msgAge), g.msg(msgMessage)}, rows)
+	fmt.Printf(g.msg(msgStashTotal)+"\n", total, repos)
+}
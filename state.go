@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// runState checkpoints the results of the current run so an interrupted
+// run can be resumed. The file holds a header line naming the root followed
+// by one JSON line per finished repository, appended as pulls complete so
+// a crash loses at most the line being written.
+type runState struct {
+	path string
+	mu   sync.Mutex
+	file *os.File
+}
+
+type stateHeader struct {
+	Root    string    `json:"root"`
+	Started time.Time `json:"started"`
+}
+
+type stateEntry struct {
+	Dir    string    `json:"dir"`
+	Status string    `json:"status"`
+	Time   time.Time `json:"time"`
+}
+
+// defaultStateDir follows the XDG base directory layout.
+func defaultStateDir() string {
+	base := os.Getenv("XDG_STATE_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		base = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(base, "gitpuller")
+}
+
+func defaultStateFile() string {
+	if dir := defaultStateDir(); dir != "" {
+		return filepath.Join(dir, "state.jsonl")
+	}
+	return ""
+}
+
+func (g *GitPullCommand) addStateFlags() {
+	flags := g.rootCmd.Flags()
+	flags.BoolVar(&g.resume, "resume", false, "Skip repositories that were pulled successfully by the last interrupted run of the same directory")
+	flags.StringVar(&g.stateFile, "state-file", defaultStateFile(), "File where run progress is checkpointed for --resume")
+}
+
+// readState returns the statuses recorded for root, or nil when the state
+// file belongs to another directory or does not exist.
+func readState(path, root string) (map[string]stateEntry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return nil, scanner.Err()
+	}
+	var header stateHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Root != root {
+		return nil, nil
+	}
+
+	entries := map[string]stateEntry{}
+	for scanner.Scan() {
+		var e stateEntry
+		// A torn last line from a crash is ignored.
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			entries[e.Dir] = e
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// openState starts checkpointing a run of root. When resuming, the existing
+// entries are kept so repeated interruptions accumulate progress.
+func openState(path, root string, resume bool) (*runState, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if !resume {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &runState{path: path, file: file}
+	if info, err := file.Stat(); err == nil && info.Size() == 0 {
+		if err := s.write(stateHeader{Root: root, Started: time.Now()}); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *runState) write(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(append(data, '\n'))
+	return err
+}
+
+// finish closes the state file. A run that completed has nothing left to
+// resume, so its state is removed.
+func (s *runState) finish(completed bool) {
+	s.file.Close()
+	if completed {
+		os.Remove(s.path)
+	}
+}
+
+// startState prepares checkpointing for the run identified by root and,
+// with --resume, loads the repositories to skip.
+func (g *GitPullCommand) startState(root string) {
+	if g.stateFile == "" || g.sim.repos > 0 {
+		return
+	}
+
+	if g.resume {
+		entries, err := readState(g.stateFile, root)
+		if err != nil {
+			g.logger.Errorf("Error reading run state: %v", err)
+		}
+		g.resumed = map[string]bool{}
+		for dir, e := range entries {
+			if isSuccess(e.Status) {
+				g.resumed[dir] = true
+			}
+		}
+		g.logger.Infof("Resuming: %d repositories were already pulled", len(g.resumed))
+	}
+
+	state, err := openState(g.stateFile, root, g.resume)
+	if err != nil {
+		g.logger.Errorf("Error opening run state: %v", err)
+		return
+	}
+	g.state = state
+}
+
+// checkpoint records the final status of a repository.
+func (g *GitPullCommand) checkpoint(dir, status string) {
+	if g.state == nil || status == statusCancelled {
+		return
+	}
+	if err := g.state.write(stateEntry{Dir: cacheKey(dir), Status: status, Time: time.Now()}); err != nil {
+		g.logger.Errorf("Error saving run state: %v", err)
+	}
+}
+
+func (g *GitPullCommand) finishState() {
+	if g.state != nil {
+		g.state.finish(!g.cancelled())
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"time"
+)
+
+const (
+	strategyFIFO   = "fifo"
+	strategyLPT    = "lpt"
+	strategyRandom = "random"
+)
+
+// estimateRuns is the number of recent runs whose durations are averaged.
+const estimateRuns = 5
+
+// strategyOptions decide the order discovered repositories start in. With
+// lpt the repositories whose pulls took longest in recent runs start first,
+// so a bounded worker pool does not end the run waiting on a slow one.
+type strategyOptions struct {
+	name      string
+	estimates map[string]time.Duration
+}
+
+func (g *GitPullCommand) addStrategyFlags() {
+	g.rootCmd.Flags().StringVar(&g.strategy.name, "schedule-strategy", strategyFIFO, "Order to start pulls in: fifo (as discovered), lpt (slowest in recent runs first) or random; setting it reports the speedup over pulling one at a time")
+}
+
+func (o strategyOptions) validate() error {
+	switch o.name {
+	case strategyFIFO, strategyLPT, strategyRandom:
+		return nil
+	}
+	return fmt.Errorf("invalid --schedule-strategy %q: expected %s, %s or %s", o.name, strategyFIFO, strategyLPT, strategyRandom)
+}
+
+// ordered reports whether pulls wait for discovery to finish so they can be
+// started in order.
+func (o strategyOptions) ordered() bool {
+	return o.name != strategyFIFO
+}
+
+// loadEstimates averages each repository's pull durations over the recent
+// runs in history.
+func (g *GitPullCommand) loadEstimates() {
+	if g.strategy.name != strategyLPT || g.historyDir == "" {
+		return
+	}
+
+	records, err := g.historyStore().load(estimateRuns)
+	if err != nil {
+		g.logger.Errorf("Error reading run history: %v", err)
+		return
+	}
+
+	totals := map[string]float64{}
+	counts := map[string]int{}
+	for _, rec := range records {
+		for _, e := range rec.Results {
+			if e.Duration > 0 {
+				totals[e.Dir] += e.Duration
+				counts[e.Dir]++
+			}
+		}
+	}
+	g.strategy.estimates = map[string]time.Duration{}
+	for dir, total := range totals {
+		g.strategy.estimates[dir] = time.Duration(total / float64(counts[dir]) * float64(time.Second))
+	}
+}
+
+// startQueued spawns the held repositories in the order of the strategy.
+// Under lpt, repositories without a recorded duration go first: they may
+// well be the slowest.
+func (g *GitPullCommand) startQueued(pending []queuedRepo) {
+	switch g.strategy.name {
+	case strategyLPT:
+		estimate := func(dir string) time.Duration {
+			if d, ok := g.strategy.estimates[dir]; ok {
+				return d
+			}
+			return time.Duration(1<<63 - 1)
+		}
+		sort.SliceStable(pending, func(i, j int) bool {
+			return estimate(pending[i].dir) > estimate(pending[j].dir)
+		})
+	case strategyRandom:
+		rand.Shuffle(len(pending), func(i, j int) {
+			pending[i], pending[j] = pending[j], pending[i]
+		})
+	}
+	for _, p := range pending {
+		g.spawn(p.dir, p.fn)
+	}
+}
+
+// printSpeedup compares the time the pulls took together with the wall
+// clock time of the run, e.g. "Pulls took 1m12s in 18.4s: 3.9x speedup
+// (lpt)".
+func (g *GitPullCommand) printSpeedup() {
+	if !g.rootCmd.Flags().Changed("schedule-strategy") {
+		return
+	}
+
+	var busy time.Duration
+	var first, last time.Time
+	for _, r := range g.summary {
+		if r.Duration <= 0 {
+			continue
+		}
+		busy += r.Duration
+		if first.IsZero() || r.Started.Before(first) {
+			first = r.Started
+		}
+		if end := r.Started.Add(r.Duration); end.After(last) {
+			last = end
+		}
+	}
+	wall := last.Sub(first)
+	if wall <= 0 {
+		return
+	}
+	fmt.Printf(g.msg(msgSpeedup)+"\n", formatDuration(busy), formatDuration(wall), busy.Seconds()/wall.Seconds(), g.strategy.name)
+}
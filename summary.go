@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// viewOptions selects and orders the rows of the final summary.
+type viewOptions struct {
+	sort        string
+	only        string
+	quiet       bool
+	summaryOnly bool
+	columns     []string
+	format      string
+	template    *template.Template
+}
+
+const (
+	sortStatus   = "status"
+	sortDir      = "dir"
+	sortDuration = "duration"
+	sortCommits  = "commits"
+	sortSize     = "size"
+
+	onlyFailed  = "failed"
+	onlyUpdated = "updated"
+	onlySkipped = "skipped"
+)
+
+func (g *GitPullCommand) addSummaryFlags() {
+	flags := g.rootCmd.Flags()
+	flags.StringVar(&g.view.sort, "sort", "", "Sort the summary (options: status, dir, duration, commits, size)")
+	flags.StringVar(&g.view.only, "only", "", "Only list some repositories in the summary (options: failed, updated, skipped)")
+	flags.BoolVarP(&g.view.quiet, "quiet", "q", false, "Print nothing but failed repositories; the exit status tells whether any failed")
+	flags.BoolVar(&g.view.summaryOnly, "summary-only", false, "Print a single line of counts instead of the summary table")
+	flags.StringSliceVar(&g.view.columns, "columns", nil, "Comma-separated summary columns, e.g. dir,branch,status,duration,commits (options: "+columnNames()+")")
+	flags.StringVar(&g.view.format, "format", "", "Print every result with a Go template instead of the table, e.g. '{{.Dir}} {{msg .Status}} {{duration .Duration}}'")
+}
+
+func (v viewOptions) validate() error {
+	switch v.sort {
+	case "", sortStatus, sortDir, sortDuration, sortCommits, sortSize:
+	default:
+		return fmt.Errorf("invalid --sort %q (options: status, dir, duration, commits, size)", v.sort)
+	}
+
+	switch v.only {
+	case "", onlyFailed, onlyUpdated, onlySkipped:
+	default:
+		return fmt.Errorf("invalid --only %q (options: failed, updated, skipped)", v.only)
+	}
+
+	_, err := lookupColumns(v.columns)
+	return err
+}
+
+// apply returns the results selected by --only in --sort order. Ties keep
+// discovery order.
+func (v viewOptions) apply(results []*repoResult) []*repoResult {
+	selected := make([]*repoResult, 0, len(results))
+	for _, r := range results {
+		if v.matches(r) {
+			selected = append(selected, r)
+		}
+	}
+
+	var less func(a, b *repoResult) bool
+	switch v.sort {
+	case sortStatus:
+		less = func(a, b *repoResult) bool { return a.Status < b.Status }
+	case sortDir:
+		less = func(a, b *repoResult) bool { return a.Dir < b.Dir }
+	case sortDuration:
+		less = func(a, b *repoResult) bool { return a.Duration > b.Duration }
+	case sortCommits:
+		less = func(a, b *repoResult) bool { return a.Commits > b.Commits }
+	case sortSize:
+		less = func(a, b *repoResult) bool { return a.totalSize() > b.totalSize() }
+	default:
+		return selected
+	}
+
+	sort.SliceStable(selected, func(i, j int) bool { return less(selected[i], selected[j]) })
+	return selected
+}
+
+func (v viewOptions) matches(r *repoResult) bool {
+	switch v.only {
+	case onlyFailed:
+		return isFailure(r.Status) || r.Status == statusFetchedNotMerged || r.Status == statusHookFailed || r.Status == statusConflict || r.Status == statusLFSFailed
+	case onlyUpdated:
+		return r.Status == statusUpdated || r.Status == statusPinnedMoved
+	case onlySkipped:
+		return r.Status == statusSkipped || r.Status == statusQuarantined || r.Status == statusCancelled || r.Status == statusResumed || r.Status == statusBranchCheck || r.Status == statusArchived
+	}
+	return true
+}
+
+func (g *GitPullCommand) printSummary() {
+	view := g.view
+	if view.quiet {
+		view.only = onlyFailed
+	}
+	results := view.apply(g.summary)
+
+	switch {
+	case view.quiet && len(results) == 0:
+	case view.summaryOnly:
+		fmt.Println(g.summaryLine(g.summary))
+		g.printTransfer()
+		g.printSpeedup()
+	case view.template != nil:
+		for _, r := range results {
+			fmt.Print(g.formatResult(r))
+		}
+	default:
+		g.printResults(results)
+		g.printTransfer()
+		g.printSpeedup()
+		if g.sizes {
+			g.printLargest(results)
+		}
+	}
+}
+
+// summaryLine counts the results in one line, e.g.
+// "84 updated, 3 failed, 12 up-to-date". Other outcomes are only mentioned
+// when they occurred.
+func (g *GitPullCommand) summaryLine(results []*repoResult) string {
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[outcome(r)]++
+	}
+
+	var parts []string
+	for _, key := range []string{msgOutcomeUpdated, msgOutcomeFailed, msgOutcomeUpToDate} {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[key], g.msg(key)))
+	}
+	for _, key := range []string{msgOutcomeFetched, msgOutcomeSkipped, msgOutcomeCancelled} {
+		if counts[key] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[key], g.msg(key)))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// outcome buckets a result for the summary line.
+func outcome(r *repoResult) string {
+	failed := viewOptions{only: onlyFailed}
+	skipped := viewOptions{only: onlySkipped}
+	switch {
+	case failed.matches(r):
+		return msgOutcomeFailed
+	case r.Status == statusCancelled:
+		return msgOutcomeCancelled
+	case skipped.matches(r):
+		return msgOutcomeSkipped
+	case r.Status == statusFetched:
+		return msgOutcomeFetched
+	case r.Status == statusUpdated || r.Status == statusPinnedMoved:
+		return msgOutcomeUpdated
+	}
+	return msgOutcomeUpToDate
+}
+
+// anyFailed reports whether the run should exit with a failure status.
+func (g *GitPullCommand) anyFailed() bool {
+	failed := viewOptions{only: onlyFailed}
+	for _, r := range g.summary {
+		if failed.matches(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *GitPullCommand) printResults(results []*repoResult) {
+	names := g.view.columns
+	if len(names) == 0 {
+		names = g.defaultColumns(results)
+	}
+	columns, err := lookupColumns(names)
+	if err != nil {
+		g.logger.Errorf("Error: %v", err)
+		return
+	}
+
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = g.msg(c.header)
+	}
+
+	rows := make([][]string, 0, len(results))
+	for _, r := range results {
+		row := make([]string, len(columns))
+		for i, c := range columns {
+			row[i] = c.value(g, r)
+			if c.name == "status" {
+				row[i] = g.paint(r, row[i])
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	g.renderTable(header, rows)
+}
+
+// kindMessage returns the catalog key labelling a repository kind.
+func kindMessage(kind string) string {
+	switch kind {
+	case kindWorktree:
+		return msgKindWorktree
+	case kindBare:
+		return msgKindBare
+	}
+	return msgKindRepo
+}
+
+// formatDuration rounds durations for display; repositories that were not
+// pulled show an empty cell.
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.Round(10 * time.Millisecond).String()
+}
+
+func (g *GitPullCommand) renderTable(header []string, rows [][]string) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(header)
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
+	table.SetAutoWrapText(false)
+
+	for _, row := range rows {
+		table.Append(row)
+	}
+
+	table.Render()
+}
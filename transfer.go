@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// transferOptions caps how much data a run may download, for metered
+// connections. The totals are guarded by g.mu.
+type transferOptions struct {
+	maxTotal string
+	limit    int64
+	total    int64
+	objects  int
+	exceeded bool
+}
+
+func (g *GitPullCommand) addTransferFlags() {
+	g.rootCmd.Flags().StringVar(&g.transfer.maxTotal, "max-total-transfer", "", "Abort the run once fetches have received more than this, e.g. 500MiB or 2GiB")
+}
+
+var sizePattern = regexp.MustCompile(`^([\d.]+)\s*([A-Za-z]*)$`)
+
+// parseSize reads a size such as 500MiB, 2G or 1024. K, M and G are binary
+// units like git's own output.
+func parseSize(value string) (int64, error) {
+	m := sizePattern.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return 0, fmt.Errorf("invalid size %q: expected e.g. 500MiB or 2GiB", value)
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %v", value, err)
+	}
+
+	unit := strings.ToUpper(strings.TrimSuffix(strings.TrimSuffix(m[2], "B"), "i"))
+	factor := map[string]float64{"": 1, "K": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40}[unit]
+	if factor == 0 {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q", value, m[2])
+	}
+	return int64(n * factor), nil
+}
+
+func (o *transferOptions) validate() error {
+	if o.maxTotal == "" {
+		return nil
+	}
+	limit, err := parseSize(o.maxTotal)
+	if err != nil {
+		return fmt.Errorf("--max-total-transfer: %v", err)
+	}
+	if limit <= 0 {
+		return fmt.Errorf("--max-total-transfer must be positive")
+	}
+	o.limit = limit
+	return nil
+}
+
+// addTransfer counts a fetch towards the run's totals and cancels the rest
+// of the run once --max-total-transfer is exceeded. The caller must hold
+// g.mu.
+func (g *GitPullCommand) addTransfer(dir string, received int64, objects int) {
+	t := &g.transfer
+	t.total += received
+	t.objects += objects
+	if t.limit > 0 && t.total > t.limit && !t.exceeded {
+		t.exceeded = true
+		g.logger.Errorf("Received %s after %s, more than --max-total-transfer %s: aborting the run", formatBytes(t.total), dir, formatBytes(t.limit))
+		g.cancel()
+	}
+}
+
+// printTransfer prints the data received across the run.
+func (g *GitPullCommand) printTransfer() {
+	if g.transfer.total == 0 && g.transfer.objects == 0 {
+		return
+	}
+	fmt.Printf(g.msg(msgTransferTotal)+"\n", formatBytes(g.transfer.total), g.transfer.objects)
+}
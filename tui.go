@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// tuiView renders a live, scrollable list of repositories while pulls run.
+type tuiView struct {
+	g      *GitPullCommand
+	app    *tview.Application
+	pages  *tview.Pages
+	table  *tview.Table
+	footer *tview.TextView
+	logs   *tview.TextView
+	done   bool
+}
+
+var statusColors = map[string]tcell.Color{
+	statusQueued:   tcell.ColorGray,
+	statusPulling:  tcell.ColorYellow,
+	statusUpdated:  tcell.ColorGreen,
+	statusUpToDate: tcell.ColorDarkGreen,
+	statusFailed:   tcell.ColorRed,
+	statusSkipped:  tcell.ColorDarkCyan,
+
+	statusFetchedNotMerged: tcell.ColorOrange,
+	statusAuthRequired:     tcell.ColorRed,
+	statusQuarantined:      tcell.ColorPurple,
+	statusConflict:         tcell.ColorRed,
+	statusLFSFailed:        tcell.ColorOrange,
+	statusCancelled:        tcell.ColorGray,
+	statusResumed:          tcell.ColorDarkCyan,
+	statusUnreachable:      tcell.ColorRed,
+	statusBranchCheck:      tcell.ColorDarkCyan,
+	statusPinned:           tcell.ColorDarkGreen,
+	statusPinnedMoved:      tcell.ColorGreen,
+	statusHostKey:          tcell.ColorRed,
+	statusArchived:         tcell.ColorDarkCyan,
+	statusOwnership:        tcell.ColorRed,
+}
+
+const tuiRefresh = 200 * time.Millisecond
+
+func (g *GitPullCommand) runTUI(pull func()) {
+	v := &tuiView{
+		g:      g,
+		app:    tview.NewApplication(),
+		pages:  tview.NewPages(),
+		table:  tview.NewTable().SetSelectable(true, false).SetFixed(1, 0),
+		footer: tview.NewTextView().SetDynamicColors(true),
+		logs:   tview.NewTextView().SetMaxLines(200),
+	}
+
+	v.table.SetBorder(true).SetTitle(" " + g.msg(msgRepositories) + " ")
+	v.logs.SetBorder(true).SetTitle(" " + g.msg(msgLog) + " ")
+	v.logs.SetChangedFunc(func() { v.app.Draw() })
+	v.table.SetSelectedFunc(func(row, _ int) { v.showOutput(row) })
+	v.table.SetInputCapture(v.handleKey)
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(v.table, 0, 1, true).
+		AddItem(v.logs, 6, 0, false).
+		AddItem(v.footer, 1, 0, false)
+	v.pages.AddPage("main", layout, true, true)
+
+	// Logging straight to the terminal would corrupt the screen.
+	g.logger.SetOutput(v.logs)
+
+	stop := make(chan struct{})
+	go v.tick(stop)
+	go func() {
+		pull()
+		v.app.QueueUpdateDraw(func() {
+			v.done = true
+			v.refresh()
+		})
+	}()
+
+	v.refresh()
+	err := v.app.SetRoot(v.pages, true).Run()
+	close(stop)
+	g.setupLogger()
+	if err != nil {
+		g.logger.Errorf("Error running TUI: %v", err)
+	}
+
+	// Retries started from the view may still be in flight.
+	g.wait()
+}
+
+// tick redraws the repository list periodically until stop is closed.
+func (v *tuiView) tick(stop <-chan struct{}) {
+	ticker := time.NewTicker(tuiRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			v.app.QueueUpdateDraw(v.refresh)
+		}
+	}
+}
+
+func (v *tuiView) refresh() {
+	g := v.g
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	v.table.Clear()
+	for col, title := range []string{g.msg(msgDirectory), g.msg(msgRemote), g.msg(msgStatus)} {
+		v.table.SetCell(0, col, tview.NewTableCell(title).
+			SetAttributes(tcell.AttrBold).
+			SetSelectable(false))
+	}
+
+	counts := map[string]int{}
+	for i, r := range g.summary {
+		counts[r.Status]++
+		v.table.SetCell(i+1, 0, tview.NewTableCell(r.Dir).SetExpansion(1))
+		v.table.SetCell(i+1, 1, tview.NewTableCell(displayRemote(r)).SetExpansion(1))
+		v.table.SetCell(i+1, 2, tview.NewTableCell(g.msg(r.Status)).SetTextColor(statusColors[r.Status]))
+	}
+
+	state := g.msg(msgRunning)
+	if v.done {
+		state = g.msg(msgDone)
+	}
+
+	parts := []string{fmt.Sprintf("%s  %d %s", state, len(g.summary), g.msg(msgRepos))}
+	for _, status := range []string{statusQueued, statusPulling, statusUpdated, statusUpToDate, statusFailed, statusSkipped} {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[status], g.msg(status)))
+	}
+	v.footer.SetText(strings.Join(parts, ", ") + "  |  " + v.help())
+}
+
+// help lists the key bindings in the footer.
+func (v *tuiView) help() string {
+	keys := []struct{ key, label string }{
+		{"enter", msgKeyOutput},
+		{"r", msgKeyRetry},
+		{"s", msgKeySkip},
+		{"q", msgKeyQuit},
+	}
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("[::b]%s[::-] %s", k.key, v.g.msg(k.label))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// selected returns the directory of the highlighted row.
+func (v *tuiView) selected() string {
+	row, _ := v.table.GetSelection()
+	if row < 1 {
+		return ""
+	}
+	return v.table.GetCell(row, 0).Text
+}
+
+func (v *tuiView) handleKey(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Rune() {
+	case 'q':
+		v.app.Stop()
+		return nil
+	case 'r':
+		if dir := v.selected(); dir != "" {
+			go v.g.retry(dir)
+		}
+		return nil
+	case 's':
+		if dir := v.selected(); dir != "" {
+			go v.g.skip(dir)
+		}
+		return nil
+	}
+	return event
+}
+
+// showOutput opens git's output for the repository in the given row.
+func (v *tuiView) showOutput(row int) {
+	if row < 1 {
+		return
+	}
+
+	dir := v.table.GetCell(row, 0).Text
+	v.g.mu.Lock()
+	r := v.g.find(dir)
+	output := ""
+	if r != nil {
+		output = r.Output
+	}
+	v.g.mu.Unlock()
+
+	if output == "" {
+		output = v.g.msg(msgNoOutput)
+	}
+
+	text := tview.NewTextView().SetText(output).SetScrollable(true)
+	text.SetBorder(true).SetTitle(fmt.Sprintf(" %s (%s) ", dir, v.g.msg(msgEscToClose)))
+	text.SetDoneFunc(func(tcell.Key) {
+		v.pages.RemovePage("output")
+	})
+	v.pages.AddPage("output", text, true, true)
+}
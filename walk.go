@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// defaultWalkJobs bounds the directories read at once during discovery.
+// Reading is dominated by round trips on network filesystems, so this is
+// well above the number of CPUs.
+const defaultWalkJobs = 16
+
+func (g *GitPullCommand) addWalkFlags() {
+	flags := g.rootCmd.PersistentFlags()
+	flags.IntVar(&g.walkJobs, "walk-jobs", defaultWalkJobs, "Maximum number of directories read at once while discovering repositories")
+	flags.BoolVar(&g.nested, "nested", true, "Keep looking for repositories inside the working trees of other repositories, such as vendored clones and submodules; --nested=false stops at the outermost repository")
+}
+
+// walk traverses dir, calls found for every repository in it and refreshes
+// the discovery cache.
+func (g *GitPullCommand) walk(dir string, found func(string)) {
+	var repos []string
+	err := g.walkTree(dir, func(repo string) {
+		repos = append(repos, repo)
+		found(repo)
+	})
+	if errors.Is(err, context.Canceled) {
+		// A partial walk must not replace the cached scan.
+		return
+	}
+	if err != nil {
+		g.logger.Errorf("Error: %v", err)
+		return
+	}
+
+	absRepos := make([]string, len(repos))
+	for i, repo := range repos {
+		absRepos[i] = cacheKey(repo)
+	}
+	g.saveScan(cacheKey(dir), absRepos)
+}
+
+// treeWalker reads the directories below a root concurrently, at most
+// g.walkJobs at a time. Calls to found are serialized, but their order
+// depends on which directories are read first.
+type treeWalker struct {
+	g     *GitPullCommand
+	found func(string)
+	slots chan struct{}
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+}
+
+// walkTree calls found for every repository below root. Like filepath.Walk
+// it does not follow symbolic links or junctions below root, and the
+// contents of .git directories and bare repositories are never read. Paths
+// matched by ignore files along the way are skipped.
+func (g *GitPullCommand) walkTree(root string, found func(string)) error {
+	// The root is followed even when it is a symbolic link or, on Windows,
+	// a junction, as the user named it explicitly.
+	info, err := os.Stat(longPath(root))
+	if err != nil {
+		g.logger.Errorf("Error accessing path: %v", err)
+		return nil
+	}
+	if !info.IsDir() {
+		return nil
+	}
+
+	w := &treeWalker{g: g, found: found, slots: make(chan struct{}, g.walkJobs)}
+	if isBareRepo(root) {
+		w.report(root)
+		return nil
+	}
+	w.readDir(root, nil, nil)
+	w.wg.Wait()
+
+	return g.ctx.Err()
+}
+
+func (w *treeWalker) report(repo string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.found(repo)
+}
+
+// readDir reports the repositories in dir, whose path relative to the root
+// is rel, and descends into its subdirectories. patterns are the ignore
+// patterns inherited from the directories above.
+func (w *treeWalker) readDir(dir string, rel []string, patterns []gitignore.Pattern) {
+	if w.g.cancelled() {
+		return
+	}
+
+	entries, err := os.ReadDir(longPath(dir))
+	if err != nil {
+		w.g.logger.Errorf("Error accessing path: %v", err)
+		return
+	}
+
+	isRepo := false
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		switch {
+		case sameName(entry.Name(), ".git"):
+			// Linked worktrees and submodules have a .git file instead.
+			if entry.IsDir() || gitfileKind(path) != kindNone {
+				isRepo = true
+			}
+		case sameName(entry.Name(), ignoreFileName) && !entry.IsDir():
+			local, err := readIgnoreFile(dir, rel)
+			if err != nil {
+				w.g.logger.Errorf("Error reading %s: %v", path, err)
+			}
+			// Copy so sibling directories do not share appended patterns.
+			patterns = append(patterns[:len(patterns):len(patterns)], local...)
+		}
+	}
+	if isRepo {
+		w.report(dir)
+		if !w.g.nested {
+			return
+		}
+	}
+	matcher := gitignore.NewMatcher(patterns)
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+
+		// Only the contents of .git are skipped; the working tree around
+		// it may hold nested repositories.
+		if sameName(entry.Name(), ".git") {
+			continue
+		}
+
+		// Junctions and other Windows reparse points are listed as
+		// irregular files rather than directories.
+		if entry.Type()&(os.ModeSymlink|os.ModeIrregular) != 0 {
+			w.g.logger.Debugf("Not following link %s", path)
+			continue
+		}
+		if !entry.IsDir() {
+			continue
+		}
+		entryRel := append(rel[:len(rel):len(rel)], entry.Name())
+		if len(patterns) > 0 && matcher.Match(entryRel, true) {
+			w.g.logger.Debugf("Ignoring %s", path)
+			continue
+		}
+		if isBareRepo(path) {
+			w.report(path)
+			continue
+		}
+		w.descend(path, entryRel, patterns)
+	}
+}
+
+// descend reads dir on a new goroutine when a slot is free and on the
+// current one otherwise, so a deep tree cannot exhaust the slots and stall.
+func (w *treeWalker) descend(dir string, rel []string, patterns []gitignore.Pattern) {
+	select {
+	case w.slots <- struct{}{}:
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			defer func() { <-w.slots }()
+			w.readDir(dir, rel, patterns)
+		}()
+	default:
+		w.readDir(dir, rel, patterns)
+	}
+}
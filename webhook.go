@@ -0,0 +1,171 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// envWebhookSecret holds the secret configured for the webhook on GitHub
+// (signing the payload) or GitLab (sent as a token).
+const envWebhookSecret = "GITPULL_WEBHOOK_SECRET"
+
+// webhookMaxBody matches the largest payload GitHub delivers.
+const webhookMaxBody = 25 << 20
+
+// pushPayload holds the repository URLs of GitHub and GitLab push events.
+type pushPayload struct {
+	Repository struct {
+		CloneURL   string `json:"clone_url"`
+		SSHURL     string `json:"ssh_url"`
+		GitURL     string `json:"git_url"`
+		HTMLURL    string `json:"html_url"`
+		GitHTTPURL string `json:"git_http_url"`
+		GitSSHURL  string `json:"git_ssh_url"`
+	} `json:"repository"`
+	Project struct {
+		GitHTTPURL string `json:"git_http_url"`
+		GitSSHURL  string `json:"git_ssh_url"`
+		WebURL     string `json:"web_url"`
+	} `json:"project"`
+}
+
+func (p pushPayload) urls() []string {
+	var urls []string
+	for _, u := range []string{
+		p.Repository.CloneURL, p.Repository.SSHURL, p.Repository.GitURL, p.Repository.HTMLURL,
+		p.Repository.GitHTTPURL, p.Repository.GitSSHURL,
+		p.Project.GitHTTPURL, p.Project.GitSSHURL, p.Project.WebURL,
+	} {
+		if u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// handleWebhook pulls the local clones of the repository a GitHub or GitLab
+// push event names. Pushes during a run are queued behind it, so none is
+// lost.
+func (s *server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, webhookMaxBody))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !s.verifyWebhook(r, body) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch event := r.Header.Get("X-GitHub-Event") + r.Header.Get("X-Gitlab-Event"); event {
+	case "ping":
+		fmt.Fprintln(w, "pong")
+		return
+	case "push", "Push Hook", "Tag Push Hook":
+	default:
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, "ignoring event %q\n", event)
+		return
+	}
+
+	var payload pushPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "invalid payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	urls := payload.urls()
+	if len(urls) == 0 {
+		http.Error(w, "the payload names no repository", http.StatusBadRequest)
+		return
+	}
+
+	selected := s.clonesOf(urls)
+	if len(selected) == 0 {
+		s.g.logger.Infof("Ignoring push to %s: no local clone below %s", urls[0], s.root)
+		http.Error(w, "no local clone of "+urls[0], http.StatusNotFound)
+		return
+	}
+
+	status, err := s.startRun(selected, true)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	s.writeJSON(w, status, s.status())
+}
+
+// verifyWebhook checks the GitHub signature or GitLab token against
+// GITPULL_WEBHOOK_SECRET. Without a secret the webhook is protected like
+// the other endpoints.
+func (s *server) verifyWebhook(r *http.Request, body []byte) bool {
+	if s.webhookSecret == "" {
+		return s.authorized(r)
+	}
+	if signature := r.Header.Get("X-Hub-Signature-256"); signature != "" {
+		mac := hmac.New(sha256.New, []byte(s.webhookSecret))
+		mac.Write(body)
+		want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		return hmac.Equal([]byte(signature), []byte(want))
+	}
+	if token := r.Header.Get("X-Gitlab-Token"); token != "" {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(s.webhookSecret)) == 1
+	}
+	return false
+}
+
+// clonesOf returns the repositories whose pull remote is one of urls,
+// looking up remotes not known from an earlier run.
+func (s *server) clonesOf(urls []string) []*repoResult {
+	g := s.g
+	wanted := map[string]bool{}
+	for _, u := range urls {
+		wanted[remoteKey(u)] = true
+	}
+
+	g.rescan(s.root, g.discover)
+	g.mu.Lock()
+	results := append([]*repoResult{}, g.summary...)
+	g.mu.Unlock()
+
+	var selected []*repoResult
+	for _, r := range results {
+		g.mu.Lock()
+		remoteURL := r.Remote
+		g.mu.Unlock()
+		if remoteURL == "" {
+			remote, err := g.resolveRemote(r.Dir)
+			if err != nil {
+				continue
+			}
+			g.mu.Lock()
+			g.updateRemote(r.Dir, remote)
+			g.mu.Unlock()
+			remoteURL = remote.URL
+		}
+		if wanted[remoteKey(remoteURL)] {
+			selected = append(selected, r)
+		}
+	}
+	return selected
+}
+
+// remoteKey identifies a repository across URL forms: SSH, HTTPS and web
+// URLs of the same repository share a key, e.g. github.com/org/app.
+func remoteKey(rawURL string) string {
+	host, path := splitRemoteURL(rawURL)
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	return strings.ToLower(host + "/" + path)
+}